@@ -46,11 +46,20 @@ import (
 	"io/ioutil"
 	"log"
 	"net/url"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-const preferenceDisableMinimumRegistrarWarning = "%s_disable_minimum_registrar_warning"
+const (
+	preferenceDisableMinimumRegistrarWarning = "%s_disable_minimum_registrar_warning"
+
+	searchAny        = "Any"
+	searchDateFormat = "2006-01-02"
+)
 
 type SpaceFyne interface {
 	bcfynego.BCFyne
@@ -72,11 +81,40 @@ type SpaceFyne interface {
 
 type spaceFyne struct {
 	bcfynego.BCFyne
+	index *fullTextIndex
+	tags  *storage.TagCache
+}
+
+// fullTextIndex holds the full-text index of the signed in account, it is shared by every copy of spaceFyne and accessed from multiple goroutines.
+type fullTextIndex struct {
+	lock   sync.Mutex
+	index  *storage.Index
+	cancel context.CancelFunc
+}
+
+// get returns the current index, or nil if no account is signed in.
+func (x *fullTextIndex) get() *storage.Index {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.index
+}
+
+// set replaces the current index, cancelling the watchers of the previous one.
+func (x *fullTextIndex) set(index *storage.Index, cancel context.CancelFunc) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.cancel != nil {
+		x.cancel()
+	}
+	x.index = index
+	x.cancel = cancel
 }
 
 func NewSpaceFyne(a fyne.App, w fyne.Window, c spaceclientgo.SpaceClient) SpaceFyne {
 	f := &spaceFyne{
 		BCFyne: bcfynego.NewBCFyne(a, w),
+		index:  &fullTextIndex{},
+		tags:   &storage.TagCache{},
 	}
 	f.AddOnSignedIn(func(account bcgo.Account) {
 		node, err := f.Node(c)
@@ -88,6 +126,8 @@ func NewSpaceFyne(a fyne.App, w fyne.Window, c spaceclientgo.SpaceClient) SpaceF
 		bcstorage.NewBCRepository(c).Register()
 		// Create Space Repository
 		storage.NewSpaceRepository(c, node).Register()
+		// Open Full-Text Index
+		f.openIndex(c, node)
 		count := 0
 		if err := spacego.AllSubscriptionsForNode(node, func(*bcgo.BlockEntry, *financego.Subscription) error {
 			count++
@@ -133,9 +173,35 @@ func NewSpaceFyne(a fyne.App, w fyne.Window, c spaceclientgo.SpaceClient) SpaceF
 		}
 		f.ShowWelcome(c, node)
 	})
+	f.AddOnSignedOut(func() {
+		f.closeIndex()
+	})
 	return f
 }
 
+// openIndex loads the account's full-text index and brings it up to date in the background.
+func (f spaceFyne) openIndex(client spaceclientgo.SpaceClient, node bcgo.Node) {
+	f.closeIndex()
+	ctx, cancel := context.WithCancel(context.Background())
+	index, err := storage.OpenIndex(ctx, client, node, filepath.Join(f.App().Storage().RootURI().Path(), "index"))
+	if err != nil {
+		cancel()
+		log.Println(err)
+		return
+	}
+	f.index.set(index, cancel)
+	go func() {
+		if err := index.Sync(); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+// closeIndex stops watching the files in the full-text index.
+func (f spaceFyne) closeIndex() {
+	f.index.set(nil, nil)
+}
+
 // ShowWelcome displays a wizard to welcome a new user and walk them through the setup process.
 func (f spaceFyne) ShowWelcome(client spaceclientgo.SpaceClient, node bcgo.Node) {
 	contents := container.NewVBox()
@@ -263,8 +329,168 @@ func (f spaceFyne) Add(client spaceclientgo.SpaceClient) {
 	}
 }
 
+// SearchFile displays a dialog for finding files by name, type, tag, date, and content, and shows the chosen result.
 func (f spaceFyne) SearchFile(client spaceclientgo.SpaceClient) {
-	f.ShowError(fmt.Errorf("Not yet implemented: %s", "SpaceFyne.SearchFile"))
+	node, err := f.Node(client)
+	if err != nil {
+		f.ShowError(err)
+		return
+	}
+
+	name := widget.NewEntry()
+	name.SetPlaceHolder(searchAny)
+	text := widget.NewEntry()
+	text.SetPlaceHolder(searchAny)
+	mime := widget.NewSelect(append([]string{searchAny, "audio/*", "image/*", "text/*", "video/*"}, spacego.MimeTypes()...), nil)
+	mime.Selected = searchAny
+	tag := widget.NewSelectEntry(nil)
+	tag.SetPlaceHolder(searchAny)
+	validateDate := func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, err := time.ParseInLocation(searchDateFormat, s, time.Local)
+		return err
+	}
+	after := widget.NewEntry()
+	after.SetPlaceHolder(searchDateFormat)
+	after.Validator = validateDate
+	before := widget.NewEntry()
+	before.SetPlaceHolder(searchDateFormat)
+	before.Validator = validateDate
+
+	// Load tags in the background as the first load involves reading every file's tag channel
+	go func() {
+		tags, err := f.tags.Tags(client, node)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		tag.SetOptions(tags)
+	}()
+
+	results := ui.NewMetaList(func(id string, timestamp uint64, meta *spacego.Meta) {
+		go f.ShowFile(client, id, timestamp, meta)
+	})
+	matches := ui.NewResultList(func(id string, timestamp uint64, meta *spacego.Meta) {
+		go f.ShowFile(client, id, timestamp, meta)
+	})
+	matches.Hide()
+
+	search := func() {
+		query := url.Values{}
+		if n := name.Text; n != "" {
+			query.Set(storage.SEARCH_NAME, n)
+		}
+		if m := mime.Selected; m != "" && m != searchAny {
+			query.Set(storage.SEARCH_TYPE, m)
+		}
+		if t := tag.Text; t != "" {
+			query.Set(storage.SEARCH_TAG, t)
+		}
+		if a := after.Text; a != "" {
+			t, err := time.ParseInLocation(searchDateFormat, a, time.Local)
+			if err != nil {
+				f.ShowError(err)
+				return
+			}
+			query.Set(storage.SEARCH_AFTER, strconv.FormatInt(t.UnixNano(), 10))
+		}
+		if b := before.Text; b != "" {
+			t, err := time.ParseInLocation(searchDateFormat, b, time.Local)
+			if err != nil {
+				f.ShowError(err)
+				return
+			}
+			// Include the whole of the last day
+			query.Set(storage.SEARCH_BEFORE, strconv.FormatInt(t.AddDate(0, 0, 1).UnixNano()-1, 10))
+		}
+
+		// Show progress dialog
+		progress := dialog.NewProgressInfinite("Searching", "Searching Files", f.Window())
+		progress.Show()
+
+		var err error
+		if t := text.Text; t == "" {
+			matches.Hide()
+			results.Show()
+			results.Clear()
+			err = storage.Search(client, node, query, results.Add)
+			results.Refresh()
+		} else {
+			results.Hide()
+			matches.Show()
+			matches.Clear()
+			err = f.searchIndex(client, node, t, query, matches)
+		}
+
+		// Hide progress dialog
+		progress.Hide()
+
+		if err != nil {
+			f.ShowError(err)
+			return
+		}
+	}
+	name.OnSubmitted = func(string) {
+		go search()
+	}
+	text.OnSubmitted = func(string) {
+		go search()
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", name),
+		widget.NewFormItem("Contents", text),
+		widget.NewFormItem("Type", mime),
+		widget.NewFormItem("Tag", tag),
+		widget.NewFormItem("After", after),
+		widget.NewFormItem("Before", before),
+	)
+	contents := container.NewBorder(
+		container.NewVBox(
+			form,
+			widget.NewButtonWithIcon("Search", theme.SearchIcon(), func() {
+				go search()
+			}),
+		),
+		nil,
+		nil,
+		nil,
+		container.NewMax(results, matches),
+	)
+
+	dialog := dialog.NewCustom("Search", "Close", contents, f.Window())
+	dialog.Show()
+	dialog.Resize(bcui.DialogSize)
+}
+
+// searchIndex shows the files whose contents match the given text, restricted to those matching the query if it isn't empty.
+func (f spaceFyne) searchIndex(client spaceclientgo.SpaceClient, node bcgo.Node, text string, query url.Values, list *ui.ResultList) error {
+	index := f.index.get()
+	if index == nil {
+		return errors.New("Full-text index is not available")
+	}
+	// Index was synced when opened, and is kept up to date by watching files
+	results := index.Search(text)
+	if len(query) > 0 {
+		allowed := make(map[string]bool)
+		if err := storage.Search(client, node, query, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+			allowed[base64.RawURLEncoding.EncodeToString(e.RecordHash)] = true
+			return nil
+		}); err != nil {
+			return err
+		}
+		var filtered []*storage.IndexResult
+		for _, r := range results {
+			if allowed[r.ID] {
+				filtered = append(filtered, r)
+			}
+		}
+		results = filtered
+	}
+	list.SetResults(results)
+	return nil
 }
 
 func (f spaceFyne) ShowFile(client spaceclientgo.SpaceClient, id string, timestamp uint64, meta *spacego.Meta) {
@@ -286,42 +512,209 @@ func (f spaceFyne) ShowFile(client spaceclientgo.SpaceClient, id string, timesta
 		return
 	}
 
-	view, err := viewer.ForMime(meta.Type)
+	// Sniff the content in case the type is missing or wrong
+	mime, err := f.detectMime(client, node, hash, meta)
 	if err != nil {
 		f.ShowError(err)
 		return
 	}
-	if view == nil {
-		f.ShowError(fmt.Errorf("Not yet implemented: %s %s", "SpaceFyne.ShowFile", meta.Type))
-		return
+
+	name := meta.Name
+	if name == "" {
+		name = "(untitled)"
 	}
+	title := fmt.Sprintf("%s - %s - %s", bcgo.TimestampToString(timestamp), name, id[:8])
+	window := f.App().NewWindow(title)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	client.WatchFile(ctx, node, hash, func() {
+	var lock sync.Mutex
+	var view viewer.Viewer
+
+	load := func() {
+		lock.Lock()
+		v := view
+		lock.Unlock()
 		reader, err := client.ReadFile(node, hash)
 		if err != nil {
 			f.ShowError(err)
 			return
 		}
-		if err := view.SetSource(reader); err != nil {
+		// Allow viewers to read the file again instead of keeping all of it in memory
+		source := viewer.NewReopenableSource(reader, func() (io.Reader, error) {
+			return client.ReadFile(node, hash)
+		})
+		if err := v.SetSource(source); err != nil {
+			if editor, ok := v.(viewer.Editor); ok && errors.Is(err, viewer.ErrConflict) {
+				f.resolveConflict(editor, window)
+				return
+			}
 			f.ShowError(err)
 			return
 		}
-	})
+	}
 
-	name := meta.Name
-	if name == "" {
-		name = "(untitled)"
+	// Allow the viewer to be overridden when several can show the file
+	names := viewer.ViewersFor(mime)
+	chooser := widget.NewSelect(names, nil)
+	show := func(name string) {
+		v, err := viewer.ForViewer(mime, name)
+		if err != nil || v == nil {
+			// Fallback to showing the raw bytes so every file can be inspected
+			v = viewer.NewHexViewer()
+		}
+		lock.Lock()
+		previous := view
+		view = v
+		lock.Unlock()
+		if player, ok := previous.(viewer.Player); ok {
+			player.Stop()
+		}
+		window.SetTitle(title)
+		window.SetCloseIntercept(nil)
+		var controls fyne.CanvasObject
+		if editor, ok := v.(viewer.Editor); ok {
+			controls = f.editFile(client, node, hash, editor, window, title)
+		}
+		window.SetContent(container.NewBorder(container.NewBorder(nil, nil, controls, chooser), nil, nil, nil, v))
+	}
+	if len(names) > 0 {
+		chooser.Selected = names[0]
+	}
+	current := chooser.Selected
+	show(current)
+	chooser.OnChanged = func(name string) {
+		if name == current {
+			return
+		}
+		lock.Lock()
+		editor, ok := view.(viewer.Editor)
+		lock.Unlock()
+		change := func() {
+			current = name
+			show(name)
+			go load()
+		}
+		if !ok || !editor.Unsaved() {
+			change()
+			return
+		}
+		dialog.ShowConfirm("Unsaved Changes", "Discard changes which have not been saved?", func(discard bool) {
+			if discard {
+				change()
+				return
+			}
+			// Revert the selection to the current viewer
+			chooser.Selected = current
+			chooser.Refresh()
+		}, window)
 	}
-	window := f.App().NewWindow(fmt.Sprintf("%s - %s - %s", bcgo.TimestampToString(timestamp), name, id[:8]))
-	window.SetContent(view)
+
+	go func() {
+		// Stop playback when the window is closed
+		<-ctx.Done()
+		lock.Lock()
+		v := view
+		lock.Unlock()
+		if player, ok := v.(viewer.Player); ok {
+			player.Stop()
+		}
+	}()
+
+	client.WatchFile(ctx, node, hash, load)
+
 	window.Resize(bcui.WindowSize)
 	window.CenterOnScreen()
 	window.SetOnClosed(cancel)
 	window.Show()
 }
 
+// detectMime reads the start of the file to determine its type, in case the type in its metadata is missing or contradicted by the content.
+func (f spaceFyne) detectMime(client spaceclientgo.SpaceClient, node bcgo.Node, hash []byte, meta *spacego.Meta) (string, error) {
+	reader, err := client.ReadFile(node, hash)
+	if err != nil {
+		return "", err
+	}
+	if c, ok := reader.(io.Closer); ok {
+		defer c.Close()
+	}
+	// Sniffing only considers the first 512 bytes
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(reader, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return viewer.DetectMime(meta.Name, meta.Type, buffer[:n]), nil
+}
+
+// editFile returns controls to edit the file and save the changes as deltas.
+func (f spaceFyne) editFile(client spaceclientgo.SpaceClient, node bcgo.Node, hash []byte, editor viewer.Editor, window fyne.Window, title string) fyne.CanvasObject {
+	save := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), nil)
+	save.Disable()
+	save.OnTapped = func() {
+		if editor.Conflicted() {
+			f.resolveConflict(editor, window)
+			return
+		}
+		go func() {
+			// Show progress dialog
+			progress := dialog.NewProgress("Saving", "Saving changes", window)
+			progress.Show()
+			listener := &bcui.ProgressMiningListener{Func: progress.SetValue}
+
+			writer, err := client.WriteFile(node, listener, hash)
+			if err == nil {
+				err = editor.Save(writer)
+			}
+
+			// Hide progress dialog
+			progress.Hide()
+
+			if err != nil {
+				if errors.Is(err, viewer.ErrConflict) {
+					f.resolveConflict(editor, window)
+					return
+				}
+				dialog.ShowError(err, window)
+				return
+			}
+		}()
+	}
+	edit := widget.NewCheck("Edit", editor.SetEditing)
+	editor.SetOnUnsavedChanged(func(unsaved bool) {
+		if unsaved {
+			save.Enable()
+			window.SetTitle("* " + title)
+		} else {
+			save.Disable()
+			window.SetTitle(title)
+		}
+	})
+	window.SetCloseIntercept(func() {
+		if !editor.Unsaved() {
+			window.Close()
+			return
+		}
+		dialog.ShowConfirm("Unsaved Changes", "Discard changes which have not been saved?", func(discard bool) {
+			if discard {
+				window.Close()
+			}
+		}, window)
+	})
+	return container.NewHBox(edit, save)
+}
+
+// resolveConflict asks the user whether to overwrite the changes made elsewhere, or merge them by hand.
+func (f spaceFyne) resolveConflict(editor viewer.Editor, window fyne.Window) {
+	label := &widget.Label{
+		Text:     viewer.ErrConflict.Error() + ".\n\nOverwrite replaces the changes made elsewhere with yours when saved.\nMerge marks both sets of changes in the file so you can combine them before saving.",
+		Wrapping: fyne.TextWrapWord,
+	}
+	confirm := dialog.NewCustomConfirm("Conflict", "Overwrite", "Merge", label, editor.ResolveConflict, window)
+	confirm.Show()
+	confirm.Resize(bcui.DialogSize)
+}
+
 func (f spaceFyne) ShowStorage(client spaceclientgo.SpaceClient) {
 	node, err := f.Node(client)
 	if err != nil {
@@ -454,22 +847,51 @@ func (f spaceFyne) ShowComposeTextDialog(client spaceclientgo.SpaceClient, node
 		return nil
 	}
 	content := widget.NewMultiLineEntry()
+	content.Wrapping = fyne.TextWrapWord
+	preview := viewer.NewMarkdownViewer()
+	prop := canvas.NewRectangle(color.Transparent)
+	prop.SetMinSize(fyne.NewSize(200, 200))
+	editor := container.NewMax(prop, content)
+	markdown := widget.NewCheck("Markdown", func(checked bool) {
+		if checked {
+			// Show live preview beside the content
+			if err := preview.SetSource(strings.NewReader(content.Text)); err != nil {
+				log.Println(err)
+			}
+			editor.Objects = []fyne.CanvasObject{prop, container.NewHSplit(content, preview)}
+		} else {
+			editor.Objects = []fyne.CanvasObject{prop, content}
+		}
+		editor.Refresh()
+	})
+	content.OnChanged = func(text string) {
+		if markdown.Checked {
+			if err := preview.SetSource(strings.NewReader(text)); err != nil {
+				log.Println(err)
+			}
+		}
+	}
 	items := []*widget.FormItem{
 		widget.NewFormItem("Title", title),
-		widget.NewFormItem("Content", content),
+		widget.NewFormItem("Format", markdown),
+		widget.NewFormItem("Content", editor),
 	}
 	dialog := dialog.NewForm("Compose", "Save", "Cancel", items, func(b bool) {
 		if !b {
 			return
 		}
 		name := title.Text
+		mime := spacego.MIME_TYPE_TEXT_PLAIN
+		if markdown.Checked {
+			mime = viewer.MIME_TYPE_TEXT_MARKDOWN
+		}
 
 		// Show progress dialog
 		progress := dialog.NewProgress("Uploading", "Uploading "+name, f.Window())
 		progress.Show()
 		listener := &bcui.ProgressMiningListener{Func: progress.SetValue}
 
-		reference, err := client.Add(node, listener, name, spacego.MIME_TYPE_TEXT_PLAIN, strings.NewReader(content.Text))
+		reference, err := client.Add(node, listener, name, mime, strings.NewReader(content.Text))
 
 		// Hide progress dialog
 		progress.Hide()
@@ -479,6 +901,7 @@ func (f spaceFyne) ShowComposeTextDialog(client spaceclientgo.SpaceClient, node
 			return
 		}
 		log.Println("Uploaded:", reference)
+		f.indexFile(reference, name, mime)
 	}, f.Window())
 	dialog.Show()
 	dialog.Resize(bcui.DialogSize)
@@ -499,7 +922,7 @@ func (f spaceFyne) ShowUploadFileDialog(client spaceclientgo.SpaceClient, node b
 		uri := reader.URI()
 		name := widget.NewEntry()
 		name.SetText(uri.Name())
-		mime := widget.NewSelect(spacego.MimeTypes(), nil)
+		mime := widget.NewSelect(uploadMimeTypes(), nil)
 		mime.Selected = uri.MimeType()
 		size := widget.NewLabel("0bytes")
 		prop := canvas.NewRectangle(color.Transparent)
@@ -514,7 +937,13 @@ func (f spaceFyne) ShowUploadFileDialog(client spaceclientgo.SpaceClient, node b
 		)
 
 		var buffer []byte
+		stopPreview := func() {
+			if player, ok := preview.Objects[1].(viewer.Player); ok {
+				player.Stop()
+			}
+		}
 		loadPreview := func(mime string) {
+			stopPreview()
 			if view, err := viewer.ForMime(mime); err != nil || view == nil {
 				preview.Objects[1] = noPreview
 			} else {
@@ -537,10 +966,14 @@ func (f spaceFyne) ShowUploadFileDialog(client spaceclientgo.SpaceClient, node b
 				return
 			}
 			size.SetText(bcgo.BinarySizeToString(uint64(len(buffer))))
+			// Correct the type if it is missing or contradicted by the content
+			mime.Selected = viewer.DetectMime(uri.Name(), mime.Selected, buffer)
+			mime.Refresh()
 			loadPreview(mime.Selected)
 		}()
 
 		dialog := dialog.NewCustomConfirm("Upload File", "Upload", "Cancel", form, func(result bool) {
+			stopPreview()
 			if result {
 				f.UploadFile(client, node, name.Text, mime.Selected, bytes.NewReader(buffer))
 			}
@@ -584,9 +1017,31 @@ func (f spaceFyne) UploadFile(client spaceclientgo.SpaceClient, node bcgo.Node,
 		return
 	}
 	log.Println("Uploaded:", reference)
+	f.indexFile(reference, name, mime)
+}
+
+// indexFile adds a newly uploaded file to the full-text index, as the index is only synced when opened.
+func (f spaceFyne) indexFile(reference *bcgo.Reference, name, mime string) {
+	index := f.index.get()
+	if index == nil {
+		return
+	}
+	go func() {
+		if err := index.Add(reference.RecordHash, reference.Timestamp, &spacego.Meta{
+			Name: name,
+			Type: mime,
+		}); err != nil {
+			log.Println(err)
+		}
+	}()
 }
 
 func (f spaceFyne) UploadFolder(client spaceclientgo.SpaceClient, node bcgo.Node, folder fyne.ListableURI) {
+	f.uploadFolder(client, node, folder, folder.Name())
+}
+
+// uploadFolder adds the contents of the given folder, prefixing names with the given path to preserve the directory tree.
+func (f spaceFyne) uploadFolder(client spaceclientgo.SpaceClient, node bcgo.Node, folder fyne.ListableURI, path string) {
 	uris, err := folder.List()
 	if err != nil {
 		f.ShowError(err)
@@ -594,6 +1049,14 @@ func (f spaceFyne) UploadFolder(client spaceclientgo.SpaceClient, node bcgo.Node
 	}
 	count := len(uris)
 
+	if count == 0 {
+		// Record empty folder so it is preserved, folders with contents are implied by the names of their files
+		if err := fynestorage.CreateListable(storage.NewFolderURI(path)); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
 	// TODO show confirmation dialog which lists the files to be uploaded so the user can select/deselect
 
 	// Show progress dialog
@@ -605,14 +1068,16 @@ func (f spaceFyne) UploadFolder(client spaceclientgo.SpaceClient, node bcgo.Node
 	for i, uri := range uris {
 		progress.SetValue(float64(i) / float64(count))
 
+		name := path + "/" + uri.Name()
+
 		// Check if URI points to Folder
 		if lister, ok := uri.(fyne.ListableURI); ok {
-			f.UploadFolder(client, node, lister)
+			f.uploadFolder(client, node, lister, name)
 			continue
 		}
 		// Check if URI points to Folder
 		if lister, err := fynestorage.ListerForURI(uri); err == nil {
-			f.UploadFolder(client, node, lister)
+			f.uploadFolder(client, node, lister, name)
 			continue
 		}
 
@@ -622,7 +1087,7 @@ func (f spaceFyne) UploadFolder(client spaceclientgo.SpaceClient, node bcgo.Node
 			log.Println(err)
 			continue
 		}
-		f.UploadFile(client, node, uri.Name(), uri.MimeType(), file)
+		f.UploadFile(client, node, name, uri.MimeType(), file)
 	}
 }
 
@@ -648,3 +1113,18 @@ func (f spaceFyne) registrarDomainsForNode(client spaceclientgo.SpaceClient, nod
 	})
 	return
 }
+
+// uploadMimeTypes returns the mime types known to S P A C E, along with any others which can be viewed.
+func uploadMimeTypes() []string {
+	types := spacego.MimeTypes()
+	known := make(map[string]bool)
+	for _, t := range types {
+		known[t] = true
+	}
+	for _, t := range viewer.MimeTypes() {
+		if !known[t] {
+			types = append(types, t)
+		}
+	}
+	return types
+}
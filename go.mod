@@ -5,10 +5,16 @@ go 1.15
 require (
 	aletheiaware.com/bcfynego v1.2.3
 	aletheiaware.com/bcgo v1.2.3
+	aletheiaware.com/cryptogo v1.2.2
 	aletheiaware.com/financego v1.2.3
 	aletheiaware.com/spaceclientgo v1.2.4
 	aletheiaware.com/spacego v1.2.4
 	aletheiaware.com/testinggo v1.2.2
 	fyne.io/fyne/v2 v2.0.2
+	github.com/golang/protobuf v1.5.2
+	github.com/srwiley/oksvg v0.0.0-20200311192757-870daf9aa564
+	github.com/srwiley/rasterx v0.0.0-20200120212402-85cb7272f5e9
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/image v0.0.0-20200430140353-33d19683fad8
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,490 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/cryptogo"
+	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	INDEX_DATA_FILE = "index_%s.bin"
+	INDEX_KEY_FILE  = "index_%s.key"
+
+	indexKeySize       = 32
+	indexSnippetLength = 120
+)
+
+// IndexDocument holds the indexed contents of a file.
+type IndexDocument struct {
+	Timestamp uint64
+	Name      string
+	Type      string
+	// Head of the file's delta channel when it was indexed
+	Head    []byte
+	Content string
+}
+
+// IndexResult is a file matching a full-text query.
+type IndexResult struct {
+	ID        string
+	Timestamp uint64
+	Meta      *spacego.Meta
+	Score     float64
+	Snippet   string
+}
+
+// Index is an on-device full-text index of the contents of text files.
+// As the files are end-to-end encrypted the index is kept encrypted at rest with a key protected by the account.
+type Index struct {
+	ctx       context.Context
+	client    spaceclientgo.SpaceClient
+	node      bcgo.Node
+	directory string
+	key       []byte
+	documents map[string]*IndexDocument
+	postings  map[string]map[string]int
+	watching  map[string]bool
+	lock      sync.RWMutex
+	// saveLock serializes writes to disk, as syncs, uploads, and watchers may save at the same time
+	saveLock sync.Mutex
+}
+
+// OpenIndex loads the index for the node's account from the given directory, creating it if necessary.
+func OpenIndex(ctx context.Context, client spaceclientgo.SpaceClient, node bcgo.Node, directory string) (*Index, error) {
+	x := &Index{
+		ctx:       ctx,
+		client:    client,
+		node:      node,
+		directory: directory,
+		documents: make(map[string]*IndexDocument),
+		postings:  make(map[string]map[string]int),
+		watching:  make(map[string]bool),
+	}
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := x.loadKey(); err != nil {
+		return nil, err
+	}
+	if err := x.load(); err != nil {
+		return nil, err
+	}
+	x.lock.RLock()
+	var ids []string
+	for id := range x.documents {
+		ids = append(ids, id)
+	}
+	x.lock.RUnlock()
+	for _, id := range ids {
+		x.watch(id)
+	}
+	return x, nil
+}
+
+// IsIndexable returns true if files of the given mime type contain text which can be indexed.
+func IsIndexable(mime string) bool {
+	mime = strings.ToLower(mime)
+	if strings.HasPrefix(mime, "text/") {
+		return true
+	}
+	switch mime {
+	case "application/javascript",
+		"application/json",
+		"application/x-sh",
+		"application/x-yaml",
+		"application/xml",
+		"application/yaml":
+		return true
+	}
+	return strings.HasSuffix(mime, "+json") || strings.HasSuffix(mime, "+xml")
+}
+
+// Sync indexes any text files which are new or have changed since they were last indexed.
+func (x *Index) Sync() error {
+	alias := x.node.Account().Alias()
+	seen := make(map[string]bool)
+	changed := false
+	if err := x.client.AllMetas(x.node, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		id := base64.RawURLEncoding.EncodeToString(e.RecordHash)
+		if seen[id] || e.Record.Creator != alias || !IsIndexable(m.Type) {
+			return nil
+		}
+		seen[id] = true
+		head := refreshChannel(x.node, spacego.OpenDeltaChannel(e.RecordHash)).Head()
+		x.lock.RLock()
+		d, ok := x.documents[id]
+		x.lock.RUnlock()
+		if ok && bytes.Equal(d.Head, head) {
+			// Unchanged
+			return nil
+		}
+		if err := x.update(id, e.RecordHash, e.Record.Timestamp, m, head); err != nil {
+			fyne.LogError("Failed to index "+m.Name, err)
+			return nil
+		}
+		changed = true
+		return nil
+	}); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+// Add indexes a file created after the index was synced, such as one just uploaded, and watches it for changes.
+func (x *Index) Add(hash []byte, timestamp uint64, meta *spacego.Meta) error {
+	if !IsIndexable(meta.Type) {
+		return nil
+	}
+	head := refreshChannel(x.node, spacego.OpenDeltaChannel(hash)).Head()
+	if err := x.update(base64.RawURLEncoding.EncodeToString(hash), hash, timestamp, meta, head); err != nil {
+		return err
+	}
+	return x.save()
+}
+
+// Search returns the files containing all terms in the given query, highest scoring first.
+func (x *Index) Search(query string) []*IndexResult {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	scores := make(map[string]float64)
+	for i, t := range terms {
+		postings := x.postings[t]
+		// Rarer terms are weighted higher
+		idf := math.Log(1 + float64(len(x.documents))/float64(1+len(postings)))
+		next := make(map[string]float64)
+		for id, frequency := range postings {
+			if s, ok := scores[id]; ok || i == 0 {
+				next[id] = s + (1+math.Log(float64(frequency)))*idf
+			}
+		}
+		scores = next
+	}
+
+	var results []*IndexResult
+	for id, score := range scores {
+		d := x.documents[id]
+		results = append(results, &IndexResult{
+			ID:        id,
+			Timestamp: d.Timestamp,
+			Meta: &spacego.Meta{
+				Name: d.Name,
+				Type: d.Type,
+			},
+			Score:   score,
+			Snippet: snippet(d.Content, terms),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Timestamp > results[j].Timestamp
+		}
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// update reads the file with the given hash and replaces its entry in the index.
+func (x *Index) update(id string, hash []byte, timestamp uint64, meta *spacego.Meta, head []byte) error {
+	reader, err := x.client.ReadFile(x.node, hash)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	x.lock.Lock()
+	x.remove(id)
+	x.add(id, &IndexDocument{
+		Timestamp: timestamp,
+		Name:      meta.Name,
+		Type:      meta.Type,
+		Head:      head,
+		Content:   string(content),
+	})
+	x.lock.Unlock()
+	x.watch(id)
+	return nil
+}
+
+// add inserts the document into the index, the caller must hold the write lock.
+func (x *Index) add(id string, d *IndexDocument) {
+	x.documents[id] = d
+	for _, t := range tokenize(d.Content) {
+		p, ok := x.postings[t]
+		if !ok {
+			p = make(map[string]int)
+			x.postings[t] = p
+		}
+		p[id]++
+	}
+}
+
+// remove deletes the document from the index, the caller must hold the write lock.
+func (x *Index) remove(id string) {
+	d, ok := x.documents[id]
+	if !ok {
+		return
+	}
+	for _, t := range tokenize(d.Content) {
+		if p, ok := x.postings[t]; ok {
+			delete(p, id)
+			if len(p) == 0 {
+				delete(x.postings, t)
+			}
+		}
+	}
+	delete(x.documents, id)
+}
+
+// watch reindexes the document whenever its file changes.
+func (x *Index) watch(id string) {
+	hash, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		fyne.LogError("Failed to decode file hash", err)
+		return
+	}
+	x.lock.Lock()
+	watching := x.watching[id]
+	x.watching[id] = true
+	x.lock.Unlock()
+	if watching {
+		return
+	}
+	x.client.WatchFile(x.ctx, x.node, hash, func() {
+		x.lock.RLock()
+		d, ok := x.documents[id]
+		x.lock.RUnlock()
+		if !ok {
+			return
+		}
+		head := refreshChannel(x.node, spacego.OpenDeltaChannel(hash)).Head()
+		if bytes.Equal(d.Head, head) {
+			return
+		}
+		if err := x.update(id, hash, d.Timestamp, &spacego.Meta{
+			Name: d.Name,
+			Type: d.Type,
+		}, head); err != nil {
+			fyne.LogError("Failed to index "+d.Name, err)
+			return
+		}
+		if err := x.save(); err != nil {
+			fyne.LogError("Failed to save index", err)
+		}
+	})
+}
+
+// loadKey reads the index key from disk, or generates and stores a new key protected by the account.
+func (x *Index) loadKey() error {
+	account := x.node.Account()
+	path := filepath.Join(x.directory, fmt.Sprintf(INDEX_KEY_FILE, account.Alias()))
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		key := make([]byte, indexKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		encrypted, algorithm, err := account.EncryptKey(key)
+		if err != nil {
+			return err
+		}
+		data = make([]byte, 4, 4+len(encrypted))
+		binary.BigEndian.PutUint32(data, uint32(algorithm))
+		data = append(data, encrypted...)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+		x.key = key
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(data) < 4 {
+		return errors.New("Invalid index key")
+	}
+	key, err := account.DecryptKey(cryptogo.EncryptionAlgorithm(binary.BigEndian.Uint32(data)), data[4:])
+	if err != nil {
+		return fmt.Errorf("Could not decrypt index key: %w", err)
+	}
+	x.key = key
+	return nil
+}
+
+// load reads and decrypts the index from disk.
+func (x *Index) load() error {
+	data, err := ioutil.ReadFile(x.dataPath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	gcm, err := x.cipher()
+	if err != nil {
+		return err
+	}
+	size := gcm.NonceSize()
+	if len(data) < size {
+		return errors.New("Invalid index")
+	}
+	plain, err := gcm.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return fmt.Errorf("Could not decrypt index: %w", err)
+	}
+	documents := make(map[string]*IndexDocument)
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&documents); err != nil {
+		return err
+	}
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	for id, d := range documents {
+		x.add(id, d)
+	}
+	return nil
+}
+
+// save encrypts and writes the index to disk.
+func (x *Index) save() error {
+	x.saveLock.Lock()
+	defer x.saveLock.Unlock()
+	var buffer bytes.Buffer
+	x.lock.RLock()
+	err := gob.NewEncoder(&buffer).Encode(x.documents)
+	x.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	gcm, err := x.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	path := x.dataPath()
+	// Write to a temporary file first so a failure doesn't corrupt the existing index
+	temp := path + ".tmp"
+	if err := ioutil.WriteFile(temp, gcm.Seal(nonce, nonce, buffer.Bytes(), nil), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+func (x *Index) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(x.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (x *Index) dataPath() string {
+	return filepath.Join(x.directory, fmt.Sprintf(INDEX_DATA_FILE, x.node.Account().Alias()))
+}
+
+// tokenize splits the text into lowercase words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// snippet returns an extract of the content surrounding the first occurrence of any of the terms.
+func snippet(content string, terms []string) string {
+	lower := strings.ToLower(content)
+	index := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i >= 0 && (index < 0 || i < index) {
+			index = i
+		}
+	}
+	if index < 0 {
+		index = 0
+	} else {
+		// Lowercasing may have changed the length of some runes
+		index = contentOffset(content, index)
+	}
+	start := index - indexSnippetLength/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + indexSnippetLength
+	if end > len(content) {
+		end = len(content)
+	}
+	// Align to rune boundaries
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+	s := strings.Join(strings.Fields(content[start:end]), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(content) {
+		s = s + "…"
+	}
+	return s
+}
+
+// contentOffset returns the offset in the content of the rune at the given offset in the lowercase content.
+func contentOffset(content string, lowerOffset int) int {
+	offset := 0
+	for i, r := range content {
+		if offset >= lowerOffset {
+			return i
+		}
+		offset += utf8.RuneLen(unicode.ToLower(r))
+	}
+	return len(content)
+}
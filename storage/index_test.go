@@ -0,0 +1,309 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/cryptogo"
+	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTokenize(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text   string
+		tokens []string
+	}{
+		"Empty":       {"", []string{}},
+		"Punctuation": {"Hello, World!", []string{"hello", "world"}},
+		"Numbers":     {"Invoice #2021-04", []string{"invoice", "2021", "04"}},
+		"Apostrophe":  {"It's", []string{"it", "s"}},
+		"Unicode":     {"Café ÜBER straße", []string{"café", "über", "straße"}},
+		"Whitespace":  {"\ta\n\nb  ", []string{"a", "b"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.tokens, tokenize(tt.text))
+		})
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	x := newTestIndex(t)
+	x.add("a", &IndexDocument{Timestamp: 1, Name: "a.txt", Content: "apple banana"})
+	x.add("b", &IndexDocument{Timestamp: 2, Name: "b.txt", Content: "apple apple apple banana"})
+	x.add("c", &IndexDocument{Timestamp: 3, Name: "c.txt", Content: "apple cherry"})
+	x.add("d", &IndexDocument{Timestamp: 4, Name: "d.txt", Content: "banana cherry"})
+	t.Run("Empty", func(t *testing.T) {
+		assert.Empty(t, x.Search(""))
+		assert.Empty(t, x.Search("!?"))
+	})
+	t.Run("NoMatch", func(t *testing.T) {
+		assert.Empty(t, x.Search("durian"))
+	})
+	t.Run("Frequency", func(t *testing.T) {
+		// More occurrences score higher, equal scores order newest first
+		assert.Equal(t, []string{"b", "c", "a"}, resultIDs(x.Search("apple")))
+	})
+	t.Run("All", func(t *testing.T) {
+		// Files must contain every term
+		assert.Equal(t, []string{"b", "a"}, resultIDs(x.Search("Apple BANANA")))
+		assert.Equal(t, []string{"c"}, resultIDs(x.Search("apple cherry")))
+		assert.Empty(t, x.Search("apple banana cherry"))
+	})
+	t.Run("Rarity", func(t *testing.T) {
+		// Rarer terms weigh more, so a single occurrence of a rare term outscores a single occurrence of a common term
+		x := newTestIndex(t)
+		x.add("common", &IndexDocument{Timestamp: 1, Content: "the the the"})
+		x.add("rare", &IndexDocument{Timestamp: 2, Content: "the zebra"})
+		x.add("other", &IndexDocument{Timestamp: 3, Content: "the"})
+		assert.Equal(t, []string{"rare"}, resultIDs(x.Search("the zebra")))
+		zebra := x.Search("zebra")
+		the := x.Search("the")
+		assert.Equal(t, []string{"common", "other", "rare"}, resultIDs(the))
+		assert.True(t, zebra[0].Score > the[1].Score)
+	})
+	t.Run("Result", func(t *testing.T) {
+		results := x.Search("cherry banana")
+		assert.Equal(t, 1, len(results))
+		assert.Equal(t, "d", results[0].ID)
+		assert.Equal(t, uint64(4), results[0].Timestamp)
+		assert.Equal(t, "d.txt", results[0].Meta.Name)
+		assert.Equal(t, "banana cherry", results[0].Snippet)
+	})
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	t.Run("Short", func(t *testing.T) {
+		assert.Equal(t, "hello world", snippet("hello\n  world", []string{"world"}))
+	})
+	t.Run("NoMatch", func(t *testing.T) {
+		s := snippet(long, []string{"missing"})
+		assert.True(t, strings.HasPrefix(s, "word"))
+		assert.True(t, strings.HasSuffix(s, "…"))
+	})
+	t.Run("Start", func(t *testing.T) {
+		s := snippet("target "+long, []string{"target"})
+		assert.True(t, strings.HasPrefix(s, "target"))
+		assert.True(t, strings.HasSuffix(s, "…"))
+	})
+	t.Run("End", func(t *testing.T) {
+		s := snippet(long+"target", []string{"target"})
+		assert.True(t, strings.HasPrefix(s, "…"))
+		assert.True(t, strings.HasSuffix(s, "target"))
+	})
+	t.Run("Middle", func(t *testing.T) {
+		s := snippet(long+"target "+long, []string{"target"})
+		assert.True(t, strings.HasPrefix(s, "…"))
+		assert.True(t, strings.HasSuffix(s, "…"))
+		assert.Contains(t, s, "target")
+	})
+	t.Run("First", func(t *testing.T) {
+		s := snippet(long+"second "+long+"first", []string{"first", "second"})
+		assert.Contains(t, s, "second")
+	})
+	t.Run("Multibyte", func(t *testing.T) {
+		// Cuts must not split the runes either side
+		for i := 0; i < 4; i++ {
+			content := strings.Repeat("a", i) + strings.Repeat("日本語", 40) + "target" + strings.Repeat("ü€𝄞", 40)
+			s := snippet(content, []string{"target"})
+			assert.True(t, utf8.ValidString(s), s)
+			assert.Contains(t, s, "target")
+		}
+	})
+	t.Run("LowercaseLength", func(t *testing.T) {
+		// Lowercasing changes the length of İ and Ⱥ, so the offset of the term in the lowercase content differs from the original
+		for _, r := range []string{"İ", "Ⱥ"} {
+			content := strings.Repeat(r, 200) + "target " + long
+			s := snippet(content, []string{"target"})
+			assert.True(t, utf8.ValidString(s), s)
+			assert.Contains(t, s, "target")
+		}
+	})
+}
+
+func TestIndex_Update(t *testing.T) {
+	hash := []byte("abcd")
+	id := base64.RawURLEncoding.EncodeToString(hash)
+	client := &indexClient{
+		files: map[string]string{
+			"abcd": "first draft",
+		},
+	}
+	x := newTestIndex(t)
+	x.client = client
+	meta := &spacego.Meta{
+		Name: "notes.txt",
+		Type: "text/plain",
+	}
+	assert.Nil(t, x.update(id, hash, 1, meta, []byte("head1")))
+	assert.Equal(t, []string{id}, resultIDs(x.Search("draft")))
+	assert.True(t, client.watching["abcd"])
+
+	client.files["abcd"] = "final version"
+	assert.Nil(t, x.update(id, hash, 1, meta, []byte("head2")))
+	assert.Empty(t, x.Search("draft"))
+	assert.Empty(t, x.Search("first"))
+	assert.Equal(t, []string{id}, resultIDs(x.Search("final")))
+	assert.Equal(t, []byte("head2"), x.documents[id].Head)
+	// Postings of removed terms must not linger
+	assert.NotContains(t, x.postings, "draft")
+	assert.Equal(t, 1, len(x.documents))
+	assert.Equal(t, 1, client.watches)
+
+	t.Run("ReadError", func(t *testing.T) {
+		assert.NotNil(t, x.update("efgh", []byte("efgh"), 1, meta, nil))
+		assert.Equal(t, 1, len(x.documents))
+	})
+}
+
+func TestIndex_SaveLoad(t *testing.T) {
+	directory := t.TempDir()
+	client := &indexClient{
+		files: map[string]string{
+			"abcd": "secret report",
+		},
+	}
+	node := newIndexNode("alice", 0x42)
+	x, err := OpenIndex(context.Background(), client, node, directory)
+	assert.Nil(t, err)
+	hash := []byte("abcd")
+	id := base64.RawURLEncoding.EncodeToString(hash)
+	assert.Nil(t, x.update(id, hash, 1, &spacego.Meta{
+		Name: "report.txt",
+		Type: "text/plain",
+	}, nil))
+	assert.Nil(t, x.save())
+	t.Run("Encrypted", func(t *testing.T) {
+		data, err := ioutil.ReadFile(x.dataPath())
+		assert.Nil(t, err)
+		assert.False(t, bytes.Contains(data, []byte("secret")))
+		assert.False(t, bytes.Contains(data, []byte("report.txt")))
+	})
+	t.Run("Load", func(t *testing.T) {
+		y, err := OpenIndex(context.Background(), client, node, directory)
+		assert.Nil(t, err)
+		results := y.Search("secret")
+		assert.Equal(t, []string{id}, resultIDs(results))
+		assert.Equal(t, "report.txt", results[0].Meta.Name)
+	})
+	t.Run("WrongKey", func(t *testing.T) {
+		_, err := OpenIndex(context.Background(), client, newIndexNode("alice", 0x24), directory)
+		assert.NotNil(t, err)
+	})
+}
+
+func newTestIndex(t *testing.T) *Index {
+	t.Helper()
+	return &Index{
+		ctx:       context.Background(),
+		client:    &indexClient{},
+		node:      newIndexNode("alice", 0x42),
+		directory: t.TempDir(),
+		documents: make(map[string]*IndexDocument),
+		postings:  make(map[string]map[string]int),
+		watching:  make(map[string]bool),
+	}
+}
+
+func resultIDs(results []*IndexResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// indexClient holds the content of files keyed by their hash. Methods which are not needed by the index are left unimplemented.
+type indexClient struct {
+	spaceclientgo.SpaceClient
+	files    map[string]string
+	watching map[string]bool
+	watches  int
+}
+
+func (c *indexClient) ReadFile(node bcgo.Node, hash []byte) (io.Reader, error) {
+	content, ok := c.files[string(hash)]
+	if !ok {
+		return nil, errors.New("File Not Found")
+	}
+	return strings.NewReader(content), nil
+}
+
+func (c *indexClient) WatchFile(ctx context.Context, node bcgo.Node, hash []byte, callback func()) {
+	if c.watching == nil {
+		c.watching = make(map[string]bool)
+	}
+	c.watching[string(hash)] = true
+	c.watches++
+}
+
+// indexAccount protects keys by xoring them with its secret.
+type indexAccount struct {
+	bcgo.Account
+	alias  string
+	secret byte
+}
+
+func (a *indexAccount) Alias() string {
+	return a.alias
+}
+
+func (a *indexAccount) EncryptKey(key []byte) ([]byte, cryptogo.EncryptionAlgorithm, error) {
+	var algorithm cryptogo.EncryptionAlgorithm
+	return a.xor(key), algorithm, nil
+}
+
+func (a *indexAccount) DecryptKey(algorithm cryptogo.EncryptionAlgorithm, key []byte) ([]byte, error) {
+	return a.xor(key), nil
+}
+
+func (a *indexAccount) xor(key []byte) []byte {
+	result := make([]byte, len(key))
+	for i, b := range key {
+		result[i] = b ^ a.secret
+	}
+	return result
+}
+
+type indexNode struct {
+	bcgo.Node
+	account bcgo.Account
+}
+
+func newIndexNode(alias string, secret byte) bcgo.Node {
+	return &indexNode{
+		account: &indexAccount{
+			alias:  alias,
+			secret: secret,
+		},
+	}
+}
+
+func (n *indexNode) Account() bcgo.Account {
+	return n.account
+}
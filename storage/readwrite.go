@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spaceclientgo"
+	"bytes"
+	"errors"
+	"fyne.io/fyne/v2"
+	"io"
+)
+
+var errFileAdded = errors.New("File Already Added")
+
+type uriReadCloser struct {
+	io.Reader
+	uri fyne.URI
+}
+
+func (r *uriReadCloser) Close() error {
+	if c, ok := r.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (r *uriReadCloser) URI() fyne.URI {
+	return r.uri
+}
+
+type uriWriteCloser struct {
+	io.WriteCloser
+	uri fyne.URI
+}
+
+func (w *uriWriteCloser) URI() fyne.URI {
+	return w.uri
+}
+
+// newFileWriteCloser buffers the written content and adds it as a new file when closed, or once a reader has been read from.
+type newFileWriteCloser struct {
+	bytes.Buffer
+	client spaceclientgo.SpaceClient
+	node   bcgo.Node
+	uri    *fileURI
+	// closed is true once the file has been added, or failed to be added with err
+	closed bool
+	err    error
+}
+
+func (w *newFileWriteCloser) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errFileAdded
+	}
+	return w.Buffer.Write(p)
+}
+
+// ReadFrom reads the content until EOF and then adds the file, so copies such as repository.GenericCopy, which ignore the error from Close, still report any error adding it.
+func (w *newFileWriteCloser) ReadFrom(reader io.Reader) (int64, error) {
+	if w.closed {
+		return 0, errFileAdded
+	}
+	n, err := w.Buffer.ReadFrom(reader)
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+func (w *newFileWriteCloser) Close() error {
+	if w.closed {
+		// File has already been added
+		return w.err
+	}
+	w.closed = true
+	reference, err := w.client.Add(w.node, nil, w.uri.meta.Name, w.uri.meta.Type, &w.Buffer)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	w.uri.fileHash = reference.RecordHash
+	return nil
+}
+
+func (w *newFileWriteCloser) URI() fyne.URI {
+	return w.uri
+}
@@ -21,15 +21,28 @@ import (
 	"aletheiaware.com/bcgo"
 	"aletheiaware.com/spaceclientgo"
 	"aletheiaware.com/spacego"
+	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
+	fynestorage "fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/storage/repository"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"net/url"
 	"strings"
 )
 
 var RootURI = &spaceURI{}
 
+var (
+	errRecordNotFound = errors.New("Record Not Found")
+	// errForeignAlias is returned for URIs qualified by an alias other than that of the signed in account, as reading the files shared by other aliases is not supported
+	errForeignAlias   = errors.New("Foreign Alias Not Supported")
+	errInvalidVersion = errors.New("Invalid Version")
+)
+
 type SpaceRepository interface {
 	repository.Repository
 	repository.CustomURIRepository
@@ -54,45 +67,145 @@ func NewSpaceRepository(client spaceclientgo.SpaceClient, node bcgo.Node) SpaceR
 }
 
 func (r *spaceRepository) CanList(u fyne.URI) (bool, error) {
-	if u == RootURI {
+	u = r.unwrap(u)
+	if isRoot(u) {
 		return true, nil
 	}
-	// TODO
-	return false, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.CanList")
+	switch u.(type) {
+	case *folderURI:
+		return r.Exists(u)
+	case *searchURI:
+		return true, nil
+	case *fileURI:
+		// Files can still be passed to List to get their records, but are not listable so file dialogs can select them rather than open them as folders
+		return false, nil
+	case *deltaURI, *metaURI, *previewURI, *tagURI, *versionURI:
+		return false, nil
+	}
+	return false, repository.ErrOperationNotSupported
 }
 
 func (r *spaceRepository) CanRead(u fyne.URI) (bool, error) {
-	if u == RootURI {
+	u = r.unwrap(u)
+	if isRoot(u) {
+		return false, nil
+	}
+	switch u.(type) {
+	case *fileURI, *deltaURI, *metaURI, *previewURI, *tagURI, *versionURI:
+		return r.Exists(u)
+	case *folderURI, *searchURI:
 		return false, nil
 	}
-	// TODO
-	return false, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.CanRead")
+	return false, repository.ErrOperationNotSupported
 }
 
 func (r *spaceRepository) CanWrite(u fyne.URI) (bool, error) {
-	if u == RootURI {
+	u = r.unwrap(u)
+	if isRoot(u) {
+		return false, nil
+	}
+	if r.isForeign(u) {
+		// Files can only be amended by their owner
+		return false, nil
+	}
+	switch u.(type) {
+	case *fileURI:
+		// Files are amended by appending deltas
+		return true, nil
+	case *deltaURI, *metaURI, *previewURI, *tagURI, *versionURI:
+		// Records and past versions are immutable
+		return false, nil
+	case *folderURI, *searchURI:
 		return false, nil
 	}
-	// TODO
-	return false, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.CanWrite")
+	return false, repository.ErrOperationNotSupported
 }
 
 func (r *spaceRepository) Child(u fyne.URI, c string) (fyne.URI, error) {
-	if u == RootURI {
-		return r.ParseURI(SPACE_SCHEME_PREFIX + c)
+	u = r.unwrap(u)
+	var name string
+	switch u := u.(type) {
+	case *fileURI:
+		if len(u.fileHash) == 0 {
+			return nil, repository.ErrOperationNotSupported
+		}
+		return r.ParseURI(u.String() + "/" + c)
+	case *folderURI:
+		name = u.folder + "/" + c
+	default:
+		if !isRoot(u) {
+			return nil, repository.ErrOperationNotSupported
+		}
+		if f, err := r.ParseURI(u.String() + c); err == nil {
+			return f, nil
+		}
+		name = c
+	}
+	alias := u.Authority()
+	child, err := r.lookup(alias, name)
+	if err != nil {
+		return nil, err
+	}
+	if child == nil {
+		// Child doesn't exist yet, it will be created when written
+		child = withAlias(NewFileURI(nil, &spacego.Meta{
+			Name: name,
+			Type: mimeTypeForName(c),
+		}), alias)
 	}
-	// TODO
-	return nil, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Child")
+	return child, nil
 }
 
 func (r *spaceRepository) Copy(src, dest fyne.URI) error {
-	// TODO
-	return fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Copy")
+	src, dest = r.unwrap(src), r.unwrap(dest)
+	if d, ok := dest.(*fileURI); ok && len(d.fileHash) == 0 {
+		// Preserve type of source
+		if f, ok := src.(*fileURI); ok {
+			d.meta.Type = f.meta.Type
+		} else if m := src.MimeType(); m != "" {
+			// Remove any parameters
+			if i := strings.Index(m, ";"); i >= 0 {
+				m = m[:i]
+			}
+			d.meta.Type = m
+		}
+	}
+
+	reader, err := fynestorage.Reader(src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	writer, err := fynestorage.Writer(dest)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return err
+	}
+	// New files are only added once the writer is closed, so the error must not be ignored
+	return writer.Close()
 }
 
 func (r *spaceRepository) CreateListable(u fyne.URI) error {
-	// TODO
-	return fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.CreateListable")
+	u = r.unwrap(u)
+	f, ok := u.(*folderURI)
+	if !ok || r.isForeign(f) {
+		return repository.ErrOperationNotSupported
+	}
+	exists, err := r.Exists(f)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	// Record folder as an empty file so it exists even before any files are added to it
+	_, err = r.client.Add(r.node, nil, f.folder, MIME_TYPE_FOLDER, &bytes.Buffer{})
+	return err
 }
 
 func (r *spaceRepository) Delete(u fyne.URI) error {
@@ -105,13 +218,114 @@ func (r *spaceRepository) Destroy(string) {
 }
 
 func (r *spaceRepository) Exists(u fyne.URI) (bool, error) {
-	// TODO
-	return false, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Exists")
+	u = r.unwrap(u)
+	if isRoot(u) {
+		return true, nil
+	}
+	if _, ok := u.(*searchURI); ok {
+		// Searches always exist, even if nothing matches
+		return true, nil
+	}
+	if f, ok := u.(*folderURI); ok {
+		child, err := r.lookup(f.alias, f.folder)
+		if err != nil {
+			return false, err
+		}
+		_, ok := child.(*folderURI)
+		return ok, nil
+	}
+	s, ok := u.(SpaceURI)
+	if !ok {
+		return false, repository.ErrOperationNotSupported
+	}
+	if len(s.FileHash()) == 0 {
+		// File has not been created yet
+		return false, nil
+	}
+	if _, err := r.meta(u.Authority(), s.FileHash()); err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	switch u := u.(type) {
+	case *fileURI, *metaURI:
+		return true, nil
+	case *versionURI:
+		if len(u.deltaHash) == 0 {
+			// Every file has a version at every timestamp, even if empty
+			return true, nil
+		}
+		if _, err := r.delta(u.alias, u.fileHash, u.deltaHash); err != nil {
+			if errors.Is(err, errRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	if _, err := r.record(u); err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 func (r *spaceRepository) List(u fyne.URI) ([]fyne.URI, error) {
-	// TODO
-	return nil, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.List")
+	u = r.unwrap(u)
+	if isRoot(u) {
+		return r.listFolder(u.Authority(), "")
+	}
+	if d, ok := u.(*folderURI); ok {
+		return r.listFolder(d.alias, d.folder+"/")
+	}
+	if q, ok := u.(*searchURI); ok {
+		return r.search(q)
+	}
+	f, ok := u.(*fileURI)
+	if !ok {
+		return nil, repository.ErrOperationNotSupported
+	}
+	hash := f.FileHash()
+	alias := f.alias
+	if err := r.checkOwner(alias, hash); err != nil {
+		return nil, err
+	}
+	uris := []fyne.URI{
+		withAlias(NewMetaURI(hash), alias),
+	}
+	var deltas []fyne.URI
+	if err := spacego.IterateDeltas(r.node, r.channel(spacego.OpenDeltaChannel(hash)), func(e *bcgo.BlockEntry, d *spacego.Delta) error {
+		deltas = append(deltas, withAlias(NewDeltaURI(hash, e.RecordHash), alias))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	// Deltas are iterated from most recent, list them in the order they were applied
+	for i := len(deltas) - 1; i >= 0; i-- {
+		uris = append(uris, deltas[i])
+	}
+	if err := spacego.IteratePreviews(r.node, r.channel(spacego.OpenPreviewChannel(hash)), func(e *bcgo.BlockEntry, p *spacego.Preview) error {
+		uris = append(uris, withAlias(&previewURI{
+			spaceURI: spaceURI{
+				fileHash: hash,
+			},
+			previewHash: e.RecordHash,
+			previewType: p.Type,
+		}, alias))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := spacego.IterateTags(r.node, r.channel(spacego.OpenTagChannel(hash)), func(e *bcgo.BlockEntry, t *spacego.Tag) error {
+		uris = append(uris, withAlias(NewTagURI(hash, e.RecordHash), alias))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return uris, nil
 }
 
 func (r *spaceRepository) Move(fyne.URI, fyne.URI) error {
@@ -119,9 +333,30 @@ func (r *spaceRepository) Move(fyne.URI, fyne.URI) error {
 	return repository.ErrOperationNotSupported
 }
 
-func (r *spaceRepository) Parent(fyne.URI) (fyne.URI, error) {
-	// TODO
-	return nil, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Parent")
+func (r *spaceRepository) Parent(u fyne.URI) (fyne.URI, error) {
+	u = r.unwrap(u)
+	if isRoot(u) {
+		return nil, repository.ErrURIRoot
+	}
+	alias := u.Authority()
+	switch u := u.(type) {
+	case *fileURI:
+		return parentFolder(alias, u.meta.Name), nil
+	case *folderURI:
+		return parentFolder(alias, u.folder), nil
+	case *searchURI:
+		return NewAliasURI(alias), nil
+	case *versionURI:
+		return withAlias(NewFileURI(u.fileHash, u.meta), alias), nil
+	case *deltaURI, *metaURI, *previewURI, *tagURI:
+		hash := u.(SpaceURI).FileHash()
+		meta, err := r.meta(alias, hash)
+		if err != nil {
+			return nil, err
+		}
+		return withAlias(NewFileURI(hash, meta), alias), nil
+	}
+	return nil, repository.ErrOperationNotSupported
 }
 
 func (r *spaceRepository) ParseURI(s string) (fyne.URI, error) {
@@ -129,14 +364,56 @@ func (r *spaceRepository) ParseURI(s string) (fyne.URI, error) {
 		return nil, storage.ErrInvalidURI
 	}
 	s = strings.TrimPrefix(s, SPACE_SCHEME_PREFIX)
+
+	var query url.Values
+	if i := strings.Index(s, "?"); i >= 0 {
+		q, err := parseQuery(s[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		query = q
+		s = s[:i]
+	}
+
+	// Check if URI is qualified by the alias that owns it
+	var alias string
+	if strings.HasPrefix(s, "//") {
+		s = strings.TrimPrefix(s, "//")
+		if i := strings.Index(s, "/"); i >= 0 {
+			alias = s[:i]
+			s = s[i+1:]
+		} else {
+			alias = s
+			s = ""
+		}
+	}
+
 	s = strings.TrimSuffix(s, "/")
 
 	if s == "" {
-		return RootURI, nil
+		if len(query) > 0 {
+			return withAlias(NewSearchURI(query), alias), nil
+		}
+		return NewAliasURI(alias), nil
+	}
+
+	if strings.HasPrefix(s, "/") {
+		return withAlias(NewFolderURI(strings.TrimPrefix(s, "/")), alias), nil
 	}
 
 	parts := strings.Split(s, "/")
 
+	// Check if URI identifies a version of the file
+	var versionHash []byte
+	if i := strings.Index(parts[0], "@"); i >= 0 {
+		h, err := base64.RawURLEncoding.DecodeString(parts[0][i+1:])
+		if err != nil {
+			return nil, err
+		}
+		versionHash = h
+		parts[0] = parts[0][:i]
+	}
+
 	var fileHash []byte
 	h, err := base64.RawURLEncoding.DecodeString(parts[0])
 	if err != nil {
@@ -144,18 +421,27 @@ func (r *spaceRepository) ParseURI(s string) (fyne.URI, error) {
 	}
 	fileHash = h
 
+	if len(parts) > 1 && (len(versionHash) > 0 || query.Get("at") != "") {
+		// Records are immutable, only files have versions
+		return nil, fmt.Errorf("Could not parse version of %s: %w", s, errInvalidVersion)
+	}
+
 	if len(parts) == 1 {
-		var meta *spacego.Meta
-		if err := r.client.MetaForHash(r.node, fileHash, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
-			meta = m
-			return nil
-		}); err != nil {
+		meta, err := r.meta(alias, fileHash)
+		if err != nil {
 			return nil, err
 		}
-		if meta == nil {
-			return nil, fmt.Errorf("Could not load metadata for %s", s)
+		if len(versionHash) > 0 {
+			return withAlias(NewDeltaVersionURI(fileHash, meta, versionHash), alias), nil
+		}
+		if at := query.Get("at"); at != "" {
+			timestamp, err := parseTimestamp(at)
+			if err != nil {
+				return nil, err
+			}
+			return withAlias(NewTimestampVersionURI(fileHash, meta, timestamp), alias), nil
 		}
-		return NewFileURI(fileHash, meta), nil
+		return withAlias(NewFileURI(fileHash, meta), alias), nil
 	}
 
 	var recordHash []byte
@@ -169,20 +455,71 @@ func (r *spaceRepository) ParseURI(s string) (fyne.URI, error) {
 
 	switch parts[1] {
 	case "delta":
-		return NewDeltaURI(fileHash, recordHash), nil
+		return withAlias(NewDeltaURI(fileHash, recordHash), alias), nil
 	case "meta":
-		return NewMetaURI(fileHash), nil
+		return withAlias(NewMetaURI(fileHash), alias), nil
 	case "preview":
-		return NewPreviewURI(fileHash, recordHash), nil
+		preview, err := r.preview(alias, fileHash, recordHash)
+		if err != nil {
+			return nil, err
+		}
+		return withAlias(&previewURI{
+			spaceURI: spaceURI{
+				fileHash: fileHash,
+			},
+			previewHash: recordHash,
+			previewType: preview.Type,
+		}, alias), nil
 	case "tag":
-		return NewTagURI(fileHash, recordHash), nil
+		return withAlias(NewTagURI(fileHash, recordHash), alias), nil
 	}
 	return nil, storage.ErrInvalidURI
 }
 
 func (r *spaceRepository) Reader(u fyne.URI) (fyne.URIReadCloser, error) {
-	// TODO
-	return nil, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Reader")
+	u = r.unwrap(u)
+	var reader io.Reader
+	switch u := u.(type) {
+	case *versionURI:
+		// Read decrypted file content as it was at the given version
+		data, err := r.version(u)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	case *fileURI:
+		// Read decrypted file content
+		rd, err := r.readFile(u.alias, u.FileHash())
+		if err != nil {
+			return nil, err
+		}
+		reader = rd
+	case *previewURI:
+		// Read preview image
+		preview, err := r.preview(u.alias, u.FileHash(), u.PreviewHash())
+		if err != nil {
+			return nil, err
+		}
+		u.previewType = preview.Type
+		reader = bytes.NewReader(preview.Data)
+	case *deltaURI, *metaURI, *tagURI:
+		// Read serialized record
+		message, err := r.record(u)
+		if err != nil {
+			return nil, err
+		}
+		data, err := proto.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	default:
+		return nil, repository.ErrOperationNotSupported
+	}
+	return &uriReadCloser{
+		Reader: reader,
+		uri:    u,
+	}, nil
 }
 
 func (r *spaceRepository) Register() {
@@ -190,6 +527,300 @@ func (r *spaceRepository) Register() {
 }
 
 func (r *spaceRepository) Writer(u fyne.URI) (fyne.URIWriteCloser, error) {
-	// TODO
-	return nil, fmt.Errorf("%s: Not Yet Implemented", "SpaceRepository.Writer")
+	u = r.unwrap(u)
+	f, ok := u.(*fileURI)
+	if !ok || r.isForeign(f) {
+		return nil, repository.ErrOperationNotSupported
+	}
+	if len(f.fileHash) == 0 {
+		if f.meta.Type == "" {
+			f.meta.Type = mimeTypeForName(f.meta.Name)
+		}
+		// Create new file when writer is closed
+		return &newFileWriteCloser{
+			client: r.client,
+			node:   r.node,
+			uri:    f,
+		}, nil
+	}
+	// Amend existing file
+	writer, err := r.client.WriteFile(r.node, nil, f.fileHash)
+	if err != nil {
+		return nil, err
+	}
+	return &uriWriteCloser{
+		WriteCloser: writer,
+		uri:         f,
+	}, nil
+}
+
+// unwrap returns the space URI wrapped by the given URI, such as the lister returned by storage.ListerForURI.
+func (r *spaceRepository) unwrap(u fyne.URI) fyne.URI {
+	switch u.(type) {
+	case *spaceURI, *deltaURI, *fileURI, *folderURI, *metaURI, *previewURI, *searchURI, *tagURI, *versionURI:
+		return u
+	}
+	if u.Scheme() != SPACE_SCHEME {
+		return u
+	}
+	if p, err := r.ParseURI(u.String()); err == nil {
+		return p
+	}
+	return u
+}
+
+// isForeign returns true if the given URI is qualified by an alias other than that of the signed in account.
+func (r *spaceRepository) isForeign(u fyne.URI) bool {
+	return r.foreign(u.Authority())
+}
+
+// foreign returns true if the given alias is not empty and not that of the signed in account.
+func (r *spaceRepository) foreign(alias string) bool {
+	return alias != "" && alias != r.node.Account().Alias()
+}
+
+// readFile returns the decrypted content of the file.
+func (r *spaceRepository) readFile(alias string, fileHash []byte) (io.Reader, error) {
+	if r.foreign(alias) {
+		return nil, errForeignAlias
+	}
+	return r.client.ReadFile(r.node, fileHash)
+}
+
+// allMetas triggers the given callback for each file, or only those owned by the given alias if not empty.
+func (r *spaceRepository) allMetas(alias string, callback spacego.MetaCallback) error {
+	if r.foreign(alias) {
+		return errForeignAlias
+	}
+	return r.client.AllMetas(r.node, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		if alias != "" && e.Record.Creator != alias {
+			return nil
+		}
+		return callback(e, m)
+	})
+}
+
+// listFolder returns the files and folders directly within the folder with the given prefix.
+func (r *spaceRepository) listFolder(alias, prefix string) ([]fyne.URI, error) {
+	var uris []fyne.URI
+	folders := make(map[string]bool)
+	if err := r.allMetas(alias, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		if !strings.HasPrefix(m.Name, prefix) {
+			return nil
+		}
+		name := strings.TrimPrefix(m.Name, prefix)
+		if i := strings.Index(name, "/"); i >= 0 {
+			// Meta is within a subfolder
+			name = name[:i]
+		} else if m.Type != MIME_TYPE_FOLDER {
+			uris = append(uris, withAlias(NewFileURI(e.RecordHash, m), alias))
+			return nil
+		}
+		if name == "" || folders[name] {
+			return nil
+		}
+		folders[name] = true
+		uris = append(uris, withAlias(NewFolderURI(prefix+name), alias))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return uris, nil
+}
+
+// version returns the content of the file as it was at the given version.
+func (r *spaceRepository) version(u *versionURI) ([]byte, error) {
+	if err := r.checkOwner(u.alias, u.fileHash); err != nil {
+		return nil, err
+	}
+	var (
+		entries []*bcgo.BlockEntry
+		deltas  []*spacego.Delta
+	)
+	if err := spacego.IterateDeltas(r.node, r.channel(spacego.OpenDeltaChannel(u.fileHash)), func(e *bcgo.BlockEntry, d *spacego.Delta) error {
+		entries = append(entries, e)
+		deltas = append(deltas, d)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	var buffer []byte
+	// Deltas are iterated from most recent, apply them in the order they were written
+	for i := len(deltas) - 1; i >= 0; i-- {
+		e := entries[i]
+		// Timestamp is only zero for versions identified by delta
+		if u.timestamp > 0 && e.Record.Timestamp > u.timestamp {
+			return buffer, nil
+		}
+		buffer = applyDelta(buffer, deltas[i])
+		if len(u.deltaHash) > 0 && bytes.Equal(e.RecordHash, u.deltaHash) {
+			return buffer, nil
+		}
+	}
+	if len(u.deltaHash) > 0 {
+		return nil, fmt.Errorf("Could not load delta for %s: %w", base64.RawURLEncoding.EncodeToString(u.deltaHash), errRecordNotFound)
+	}
+	return buffer, nil
+}
+
+// lookup returns the file or folder with the given name, or nil if neither exist.
+func (r *spaceRepository) lookup(alias, name string) (fyne.URI, error) {
+	var (
+		file      fyne.URI
+		timestamp uint64
+		folder    bool
+	)
+	if err := r.allMetas(alias, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		switch {
+		case m.Name == name && m.Type != MIME_TYPE_FOLDER:
+			// Choose the most recent file with matching name
+			if t := e.Record.Timestamp; t > timestamp {
+				timestamp = t
+				file = withAlias(NewFileURI(e.RecordHash, m), alias)
+			}
+		case m.Name == name, strings.HasPrefix(m.Name, name+"/"):
+			folder = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return file, nil
+	}
+	if folder {
+		return withAlias(NewFolderURI(name), alias), nil
+	}
+	return nil, nil
+}
+
+// record returns the Delta, Meta, Preview, or Tag identified by the given URI.
+func (r *spaceRepository) record(u fyne.URI) (proto.Message, error) {
+	switch u := u.(type) {
+	case *deltaURI:
+		return r.delta(u.Authority(), u.FileHash(), u.DeltaHash())
+	case *metaURI:
+		return r.meta(u.Authority(), u.FileHash())
+	case *previewURI:
+		return r.preview(u.Authority(), u.FileHash(), u.PreviewHash())
+	case *tagURI:
+		return r.tag(u.Authority(), u.FileHash(), u.TagHash())
+	}
+	return nil, repository.ErrOperationNotSupported
+}
+
+// checkOwner returns an error if the given alias is not empty and does not own the file with the given hash.
+func (r *spaceRepository) checkOwner(alias string, fileHash []byte) error {
+	if alias == "" {
+		return nil
+	}
+	_, err := r.meta(alias, fileHash)
+	return err
+}
+
+func (r *spaceRepository) delta(alias string, fileHash, deltaHash []byte) (*spacego.Delta, error) {
+	if err := r.checkOwner(alias, fileHash); err != nil {
+		return nil, err
+	}
+	var delta *spacego.Delta
+	if err := spacego.IterateDeltas(r.node, r.channel(spacego.OpenDeltaChannel(fileHash)), func(e *bcgo.BlockEntry, d *spacego.Delta) error {
+		if bytes.Equal(e.RecordHash, deltaHash) {
+			delta = d
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if delta == nil {
+		return nil, fmt.Errorf("Could not load delta for %s: %w", base64.RawURLEncoding.EncodeToString(deltaHash), errRecordNotFound)
+	}
+	return delta, nil
+}
+
+func (r *spaceRepository) meta(alias string, fileHash []byte) (*spacego.Meta, error) {
+	var meta *spacego.Meta
+	callback := func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		if alias != "" && e.Record.Creator != alias {
+			// File is not owned by alias
+			return nil
+		}
+		meta = m
+		return nil
+	}
+	if r.foreign(alias) {
+		return nil, errForeignAlias
+	}
+	if err := r.client.MetaForHash(r.node, fileHash, callback); err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("Could not load metadata for %s: %w", base64.RawURLEncoding.EncodeToString(fileHash), errRecordNotFound)
+	}
+	return meta, nil
+}
+
+func (r *spaceRepository) preview(alias string, fileHash, previewHash []byte) (*spacego.Preview, error) {
+	if err := r.checkOwner(alias, fileHash); err != nil {
+		return nil, err
+	}
+	var preview *spacego.Preview
+	if err := spacego.IteratePreviews(r.node, r.channel(spacego.OpenPreviewChannel(fileHash)), func(e *bcgo.BlockEntry, p *spacego.Preview) error {
+		if bytes.Equal(e.RecordHash, previewHash) {
+			preview = p
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if preview == nil {
+		return nil, fmt.Errorf("Could not load preview for %s: %w", base64.RawURLEncoding.EncodeToString(previewHash), errRecordNotFound)
+	}
+	return preview, nil
+}
+
+func (r *spaceRepository) tag(alias string, fileHash, tagHash []byte) (*spacego.Tag, error) {
+	if err := r.checkOwner(alias, fileHash); err != nil {
+		return nil, err
+	}
+	var tag *spacego.Tag
+	if err := spacego.IterateTags(r.node, r.channel(spacego.OpenTagChannel(fileHash)), func(e *bcgo.BlockEntry, t *spacego.Tag) error {
+		if bytes.Equal(e.RecordHash, tagHash) {
+			tag = t
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, fmt.Errorf("Could not load tag for %s: %w", base64.RawURLEncoding.EncodeToString(tagHash), errRecordNotFound)
+	}
+	return tag, nil
+}
+
+// channel refreshes the given channel so it reflects the latest records from the cache and network.
+func (r *spaceRepository) channel(c bcgo.Channel) bcgo.Channel {
+	return refreshChannel(r.node, c)
+}
+
+func refreshChannel(node bcgo.Node, c bcgo.Channel) bcgo.Channel {
+	if err := c.Refresh(node.Cache(), node.Network()); err != nil {
+		fyne.LogError("Failed to refresh "+c.Name(), err)
+	}
+	return c
+}
+
+// applyDelta returns the result of deleting and inserting bytes at the delta's offset.
+func applyDelta(buffer []byte, delta *spacego.Delta) []byte {
+	offset := delta.Offset
+	if l := uint64(len(buffer)); offset > l {
+		offset = l
+	}
+	end := offset + delta.Delete
+	if l := uint64(len(buffer)); end > l {
+		end = l
+	}
+	result := make([]byte, 0, uint64(len(buffer))-(end-offset)+uint64(len(delta.Insert)))
+	result = append(result, buffer[:offset]...)
+	result = append(result, delta.Insert...)
+	return append(result, buffer[end:]...)
 }
@@ -0,0 +1,864 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacefynego/storage"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/storage/repository"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"testing"
+)
+
+const (
+	Alias        = "alice"
+	MemoryScheme = "memory"
+)
+
+func TestRepository_CanList(t *testing.T) {
+	client, _ := newRepository(t)
+	notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	for name, tt := range map[string]struct {
+		uri      fyne.URI
+		listable bool
+	}{
+		"Root":          {storage.RootURI, true},
+		"Folder":        {storage.NewFolderURI("photos"), true},
+		"MissingFolder": {storage.NewFolderURI("videos"), false},
+		"File":          {notes, false},
+		"Meta":          {storage.NewMetaURI(notes.FileHash()), false},
+		"Search":        {storage.NewSearchURI(url.Values{}), true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			listable, err := fynestorage.CanList(tt.uri)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.listable, listable)
+		})
+	}
+	t.Run("Dialog", func(t *testing.T) {
+		// List and check each child as dialog.fileDialog.refreshDir does, files must be selectable rather than opened as folders
+		lister, err := fynestorage.ListerForURI(storage.RootURI)
+		assert.Nil(t, err)
+		uris, err := lister.List()
+		assert.Nil(t, err)
+		folders := make(map[string]bool)
+		for _, u := range uris {
+			listable, err := fynestorage.CanList(u)
+			assert.Nil(t, err)
+			folders[u.Name()] = listable
+		}
+		assert.Equal(t, map[string]bool{
+			"notes.txt": false,
+			"photos":    true,
+		}, folders)
+	})
+}
+
+func TestRepository_CanRead(t *testing.T) {
+	client, repo := newRepository(t)
+	notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	missing, err := repo.Child(storage.RootURI, "missing.txt")
+	assert.Nil(t, err)
+	for name, tt := range map[string]struct {
+		uri      fyne.URI
+		readable bool
+	}{
+		"Root":    {storage.RootURI, false},
+		"Folder":  {storage.NewFolderURI("photos"), false},
+		"File":    {notes, true},
+		"Missing": {missing, false},
+		"Search":  {storage.NewSearchURI(url.Values{}), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			readable, err := fynestorage.CanRead(tt.uri)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.readable, readable)
+		})
+	}
+}
+
+func TestRepository_Child(t *testing.T) {
+	client, repo := newRepository(t)
+	notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	cat := client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	t.Run("File", func(t *testing.T) {
+		u, err := repo.Child(storage.RootURI, "notes.txt")
+		assert.Nil(t, err)
+		assert.Equal(t, notes.String(), u.String())
+	})
+	t.Run("FileInFolder", func(t *testing.T) {
+		u, err := repo.Child(storage.NewFolderURI("photos"), "cat.png")
+		assert.Nil(t, err)
+		assert.Equal(t, cat.String(), u.String())
+	})
+	t.Run("Folder", func(t *testing.T) {
+		u, err := repo.Child(storage.RootURI, "photos")
+		assert.Nil(t, err)
+		assert.Equal(t, "space:////photos", u.String())
+	})
+	t.Run("Hash", func(t *testing.T) {
+		u, err := repo.Child(storage.RootURI, notes.Path())
+		assert.Nil(t, err)
+		assert.Equal(t, notes.String(), u.String())
+	})
+	t.Run("Record", func(t *testing.T) {
+		u, err := repo.Child(notes, "meta")
+		assert.Nil(t, err)
+		assert.Equal(t, notes.String()+"/meta", u.String())
+	})
+	t.Run("New", func(t *testing.T) {
+		u, err := repo.Child(storage.NewFolderURI("photos"), "dog.png")
+		assert.Nil(t, err)
+		assert.Equal(t, "dog.png", u.Name())
+		assert.Empty(t, u.(storage.SpaceURI).FileHash())
+	})
+	t.Run("Search", func(t *testing.T) {
+		_, err := repo.Child(storage.NewSearchURI(url.Values{}), "notes.txt")
+		assert.Equal(t, repository.ErrOperationNotSupported, err)
+	})
+}
+
+func TestRepository_Copy(t *testing.T) {
+	t.Run("Import", func(t *testing.T) {
+		client, repo := newRepository(t)
+		src := newMemoryURI(t, "notes.txt", "Hello World")
+		dest, err := repo.Child(storage.RootURI, "notes.txt")
+		assert.Nil(t, err)
+		assert.Nil(t, fynestorage.Copy(src, dest))
+		assert.Equal(t, 1, len(client.files))
+		f := client.files[0]
+		assert.Equal(t, "notes.txt", f.meta.Name)
+		assert.Equal(t, "text/plain", f.meta.Type)
+		assert.Equal(t, "Hello World", string(f.data))
+		assert.Equal(t, f.entry.RecordHash, dest.(storage.SpaceURI).FileHash())
+	})
+	t.Run("ImportError", func(t *testing.T) {
+		client, repo := newRepository(t)
+		client.addErr = errors.New("Mining Failed")
+		src := newMemoryURI(t, "notes.txt", "Hello World")
+		dest, err := repo.Child(storage.RootURI, "notes.txt")
+		assert.Nil(t, err)
+		// Memory repository uses repository.GenericCopy which ignores the error from closing the writer
+		assert.Equal(t, client.addErr, fynestorage.Copy(src, dest))
+		assert.Equal(t, 0, len(client.files))
+	})
+	t.Run("ImportUnknownType", func(t *testing.T) {
+		client, repo := newRepository(t)
+		src := newMemoryURI(t, "notes.xyz", "Hello World")
+		dest, err := repo.Child(storage.RootURI, "notes.xyz")
+		assert.Nil(t, err)
+		assert.Nil(t, fynestorage.Copy(src, dest))
+		assert.Equal(t, 1, len(client.files))
+		assert.Equal(t, "application/octet-stream", client.files[0].meta.Type)
+	})
+	t.Run("Export", func(t *testing.T) {
+		client, _ := newRepository(t)
+		src := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+		dest := newMemoryURI(t, "export.txt", "")
+		assert.Nil(t, fynestorage.Copy(src, dest))
+		assert.Equal(t, "Hello World", memoryFiles[dest.String()])
+	})
+}
+
+func TestRepository_CreateListable(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		client, _ := newRepository(t)
+		assert.Nil(t, fynestorage.CreateListable(storage.NewFolderURI("photos")))
+		assert.Nil(t, fynestorage.CreateListable(storage.NewFolderURI("photos")))
+		assert.Equal(t, 1, len(client.files))
+		assert.Equal(t, storage.MIME_TYPE_FOLDER, client.files[0].meta.Type)
+	})
+	t.Run("NotEmpty", func(t *testing.T) {
+		client, _ := newRepository(t)
+		client.add(Alias, "photos/cat.png", "image/png", "Meow")
+		assert.Nil(t, fynestorage.CreateListable(storage.NewFolderURI("photos")))
+		assert.Equal(t, 1, len(client.files))
+	})
+}
+
+func TestRepository_FileDialog(t *testing.T) {
+	test.NewApp()
+	client, _ := newRepository(t)
+	client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	cat := client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	dog := client.add(Alias, "photos/2021/dog.png", "image/png", "Woof")
+
+	var opened string
+	w := test.NewWindow(widget.NewLabel(""))
+	w.Resize(fyne.NewSize(800, 600))
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		assert.Nil(t, err)
+		if reader != nil {
+			defer reader.Close()
+			data, err := ioutil.ReadAll(reader)
+			assert.Nil(t, err)
+			opened = string(data)
+		}
+	}, w)
+	d.Show()
+	for name, tt := range map[string]struct {
+		folder fyne.URI
+		items  []string
+	}{
+		"Root":   {storage.RootURI, []string{"photos"}},
+		"Folder": {storage.NewFolderURI("photos/2021"), []string{"(Parent)", dog.Path()}},
+		"Search": {storage.NewSearchURI(url.Values{storage.SEARCH_NAME: []string{"cat"}}), []string{"(Parent)", cat.Path()}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lister, err := fynestorage.ListerForURI(tt.folder)
+			assert.Nil(t, err)
+			// Must not panic when splitting the location into breadcrumbs
+			d.SetLocation(lister)
+			items := dialogItems(w.Canvas())
+			for _, i := range tt.items {
+				assert.Contains(t, items, i)
+			}
+		})
+	}
+	t.Run("Navigate", func(t *testing.T) {
+		lister, err := fynestorage.ListerForURI(storage.RootURI)
+		assert.Nil(t, err)
+		d.SetLocation(lister)
+		items := dialogItems(w.Canvas())
+		assert.Contains(t, items, "photos")
+		test.Tap(items["photos"])
+		items = dialogItems(w.Canvas())
+		assert.Contains(t, items, "(Parent)")
+		assert.Contains(t, items, "2021")
+		// Files are labelled by their hash
+		assert.Contains(t, items, cat.Path())
+		test.Tap(items["(Parent)"])
+		assert.Contains(t, dialogItems(w.Canvas()), "photos")
+	})
+	t.Run("Open", func(t *testing.T) {
+		lister, err := fynestorage.ListerForURI(storage.NewFolderURI("photos"))
+		assert.Nil(t, err)
+		d.SetLocation(lister)
+		items := dialogItems(w.Canvas())
+		assert.Contains(t, items, cat.Path())
+		// Selecting a file must not open it as a folder
+		test.Tap(items[cat.Path()])
+		assert.Contains(t, dialogItems(w.Canvas()), "2021")
+		test.Tap(dialogButton(w.Canvas(), "Open"))
+		assert.Equal(t, "Meow", opened)
+	})
+}
+
+func TestRepository_Exists(t *testing.T) {
+	client, repo := newRepository(t)
+	notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	client.add(Alias, "empty", storage.MIME_TYPE_FOLDER, "")
+	missing, err := repo.Child(storage.RootURI, "missing.txt")
+	assert.Nil(t, err)
+	for name, tt := range map[string]struct {
+		uri    fyne.URI
+		exists bool
+	}{
+		"Root":          {storage.RootURI, true},
+		"Folder":        {storage.NewFolderURI("photos"), true},
+		"EmptyFolder":   {storage.NewFolderURI("empty"), true},
+		"MissingFolder": {storage.NewFolderURI("videos"), false},
+		"File":          {notes, true},
+		"MissingFile":   {missing, false},
+		"Search":        {storage.NewSearchURI(url.Values{}), true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exists, err := fynestorage.Exists(tt.uri)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.exists, exists)
+		})
+	}
+}
+
+func TestRepository_Reader(t *testing.T) {
+	client, repo := newRepository(t)
+	file := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	t.Run("File", func(t *testing.T) {
+		assert.Equal(t, "Hello World", readAll(t, file))
+	})
+	t.Run("Folder", func(t *testing.T) {
+		_, err := repo.Reader(storage.NewFolderURI("photos"))
+		assert.Equal(t, repository.ErrOperationNotSupported, err)
+	})
+	t.Run("Root", func(t *testing.T) {
+		_, err := repo.Reader(storage.RootURI)
+		assert.Equal(t, repository.ErrOperationNotSupported, err)
+	})
+	t.Run("Search", func(t *testing.T) {
+		_, err := repo.Reader(storage.NewSearchURI(url.Values{}))
+		assert.Equal(t, repository.ErrOperationNotSupported, err)
+	})
+}
+
+func TestRepository_List(t *testing.T) {
+	client, repo := newRepository(t)
+	notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	cat := client.add(Alias, "photos/cat.png", "image/png", "Meow")
+	client.add(Alias, "photos/2021/dog.png", "image/png", "Woof")
+	client.add(Alias, "empty", storage.MIME_TYPE_FOLDER, "")
+	t.Run("Root", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{
+			notes.String(),
+			"space:////photos",
+			"space:////empty",
+		}, list(t, repo, storage.RootURI))
+	})
+	t.Run("Folder", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{
+			cat.String(),
+			"space:////photos/2021",
+		}, list(t, repo, storage.NewFolderURI("photos")))
+	})
+	t.Run("EmptyFolder", func(t *testing.T) {
+		assert.Empty(t, list(t, repo, storage.NewFolderURI("empty")))
+	})
+	t.Run("Search", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{
+			cat.String(),
+		}, list(t, repo, storage.NewSearchURI(url.Values{
+			storage.SEARCH_NAME: []string{"cat"},
+			storage.SEARCH_TYPE: []string{"image/*"},
+		})))
+	})
+}
+
+func TestRepository_Parent(t *testing.T) {
+	client, repo := newRepository(t)
+	cat := client.add(Alias, "photos/2021/cat.png", "image/png", "Meow")
+	for name, tt := range map[string]struct {
+		uri    fyne.URI
+		parent string
+	}{
+		"File":      {cat, "space:////photos/2021"},
+		"Folder":    {storage.NewFolderURI("photos/2021"), "space:////photos"},
+		"TopFolder": {storage.NewFolderURI("photos"), "space:///"},
+		"Meta":      {storage.NewMetaURI(cat.FileHash()), cat.String()},
+		"Search":    {storage.NewSearchURI(url.Values{}), "space:///"},
+		"Version":   {storage.NewTimestampVersionURI(cat.FileHash(), cat.Meta(), 1617235200000000000), cat.String()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			parent, err := repo.Parent(tt.uri)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.parent, parent.String())
+		})
+	}
+	t.Run("Root", func(t *testing.T) {
+		_, err := repo.Parent(storage.RootURI)
+		assert.Equal(t, repository.ErrURIRoot, err)
+	})
+}
+
+func TestRepository_ForeignAlias(t *testing.T) {
+	client, repo := newRepository(t)
+	own := client.add(Alias, "notes.txt", "text/plain", "Alice's Notes")
+	t.Run("List", func(t *testing.T) {
+		_, err := repo.List(storage.NewAliasURI("bob"))
+		assert.NotNil(t, err)
+	})
+	t.Run("ParseURI", func(t *testing.T) {
+		// Reading files shared by other aliases is not supported, and must not fall back to the files of the signed in account
+		_, err := repo.ParseURI("space://bob/" + own.Path())
+		assert.NotNil(t, err)
+		_, err = repo.ParseURI("space://bob/" + own.Path() + "?at=1617235200000000000")
+		assert.NotNil(t, err)
+	})
+	t.Run("Child", func(t *testing.T) {
+		_, err := repo.Child(storage.NewAliasURI("bob"), "notes.txt")
+		assert.NotNil(t, err)
+	})
+	t.Run("Search", func(t *testing.T) {
+		u, err := repo.ParseURI("space://bob//?name=notes")
+		assert.Nil(t, err)
+		_, err = repo.List(u)
+		assert.NotNil(t, err)
+	})
+	t.Run("OwnAlias", func(t *testing.T) {
+		u, err := repo.ParseURI("space://" + Alias + "/" + own.Path())
+		assert.Nil(t, err)
+		assert.Equal(t, "Alice's Notes", readAll(t, u))
+		assert.ElementsMatch(t, []string{
+			"space://" + Alias + "/" + own.Path(),
+		}, list(t, repo, storage.NewAliasURI(Alias)))
+	})
+}
+
+func TestRepository_ParseURI(t *testing.T) {
+	client, repo := newRepository(t)
+	file := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	for name, tt := range map[string]string{
+		"Root":      "space:///",
+		"Alias":     "space://bob/",
+		"File":      file.String(),
+		"Delta":     file.String() + "/delta/" + DeltaHash,
+		"Meta":      file.String() + "/meta",
+		"Tag":       file.String() + "/tag/" + TagHash,
+		"Folder":    "space:////Test/Notes",
+		"Search":    SearchURI,
+		"Version":   file.String() + "@" + DeltaHash,
+		"Timestamp": file.String() + "?at=1617235200000000000",
+
+		"AliasFile":   "space://" + Alias + "/" + file.Path(),
+		"AliasFolder": "space://bob//Test/Notes",
+		"AliasSearch": "space://bob//?name=notes",
+	} {
+		t.Run(name, func(t *testing.T) {
+			u, err := repo.ParseURI(tt)
+			assert.Nil(t, err)
+			assert.Equal(t, tt, u.String())
+		})
+	}
+	t.Run("AliasAuthority", func(t *testing.T) {
+		u, err := repo.ParseURI("space://" + Alias + "/" + file.Path())
+		assert.Nil(t, err)
+		assert.Equal(t, Alias, u.Authority())
+		assert.Equal(t, "notes.txt", u.Name())
+	})
+	t.Run("SearchQuery", func(t *testing.T) {
+		u, err := repo.ParseURI(SearchURI)
+		assert.Nil(t, err)
+		assert.Equal(t, url.Values{
+			storage.SEARCH_NAME: []string{"report"},
+			storage.SEARCH_TAG:  []string{"invoice"},
+			storage.SEARCH_TYPE: []string{"image/*"},
+		}, u.(storage.SearchURI).Search())
+	})
+	t.Run("WrongScheme", func(t *testing.T) {
+		_, err := repo.ParseURI("file:///notes.txt")
+		assert.NotNil(t, err)
+	})
+	t.Run("BadBase64", func(t *testing.T) {
+		_, err := repo.ParseURI("space:///abc$1234")
+		assert.NotNil(t, err)
+		_, err = repo.ParseURI(file.String() + "@abc$1234")
+		assert.NotNil(t, err)
+		_, err = repo.ParseURI(file.String() + "/delta/abc$1234")
+		assert.NotNil(t, err)
+	})
+	t.Run("BadQuery", func(t *testing.T) {
+		_, err := repo.ParseURI("space:////?name=%zz")
+		assert.NotNil(t, err)
+	})
+	t.Run("MissingHash", func(t *testing.T) {
+		_, err := repo.ParseURI("space:///" + FileHash)
+		assert.NotNil(t, err)
+	})
+	t.Run("UnknownRecord", func(t *testing.T) {
+		_, err := repo.ParseURI(file.String() + "/unknown")
+		assert.NotNil(t, err)
+	})
+	t.Run("RecordVersion", func(t *testing.T) {
+		// Records are immutable so must not silently drop the version
+		_, err := repo.ParseURI(file.String() + "@" + DeltaHash + "/meta")
+		assert.NotNil(t, err)
+		_, err = repo.ParseURI(file.String() + "/delta/" + DeltaHash + "?at=1617235200000000000")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRepository_ParseURI_Timestamp(t *testing.T) {
+	client, repo := newRepository(t)
+	file := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+	t.Run("Nanoseconds", func(t *testing.T) {
+		u, err := repo.ParseURI(file.String() + "?at=1617235200000000000")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1617235200000000000), u.(storage.VersionURI).AtTime())
+	})
+	t.Run("RFC3339", func(t *testing.T) {
+		u, err := repo.ParseURI(file.String() + "?at=2021-04-01T00:00:00Z")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1617235200000000000), u.(storage.VersionURI).AtTime())
+	})
+	t.Run("RFC3339Offset", func(t *testing.T) {
+		// Plus sign must not be decoded as a space
+		u, err := repo.ParseURI(file.String() + "?at=2021-04-01T02:00:00+02:00")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1617235200000000000), u.(storage.VersionURI).AtTime())
+		u, err = repo.ParseURI(file.String() + "?at=2021-04-01T02%3A00%3A00%2B02%3A00")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1617235200000000000), u.(storage.VersionURI).AtTime())
+	})
+	t.Run("Zero", func(t *testing.T) {
+		_, err := repo.ParseURI(file.String() + "?at=0")
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := repo.ParseURI(file.String() + "?at=yesterday")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRepository_Writer(t *testing.T) {
+	t.Run("Create", func(t *testing.T) {
+		client, repo := newRepository(t)
+		dest, err := repo.Child(storage.NewFolderURI("photos"), "cat.png")
+		assert.Nil(t, err)
+		writer, err := fynestorage.Writer(dest)
+		assert.Nil(t, err)
+		_, err = writer.Write([]byte("Meow"))
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+		assert.Equal(t, 1, len(client.files))
+		f := client.files[0]
+		assert.Equal(t, "photos/cat.png", f.meta.Name)
+		assert.Equal(t, "image/png", f.meta.Type)
+		assert.Equal(t, "Meow", string(f.data))
+	})
+	t.Run("Amend", func(t *testing.T) {
+		client, _ := newRepository(t)
+		notes := client.add(Alias, "notes.txt", "text/plain", "Hello World")
+		writer, err := fynestorage.Writer(notes)
+		assert.Nil(t, err)
+		_, err = writer.Write([]byte("Hello Space"))
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+		assert.Equal(t, 1, len(client.files))
+		f := client.files[0]
+		assert.Equal(t, []*spacego.Delta{
+			{
+				Delete: 11,
+				Insert: []byte("Hello Space"),
+			},
+		}, f.deltas)
+		assert.Equal(t, "Hello Space", readAll(t, notes))
+	})
+}
+
+func TestRepository_Writer_CloseTwice(t *testing.T) {
+	client, repo := newRepository(t)
+	dest, err := repo.Child(storage.RootURI, "notes.txt")
+	assert.Nil(t, err)
+	writer, err := fynestorage.Writer(dest)
+	assert.Nil(t, err)
+	_, err = writer.Write([]byte("Hello World"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, 1, len(client.files))
+}
+
+// dialogItems returns the tappable items of the dialog shown over the given canvas, keyed by their label.
+func dialogItems(c fyne.Canvas) map[string]fyne.Tappable {
+	items := make(map[string]fyne.Tappable)
+	for _, o := range test.LaidOutObjects(c.Overlays().Top()) {
+		w, ok := o.(fyne.Widget)
+		if !ok {
+			continue
+		}
+		tappable, ok := o.(fyne.Tappable)
+		if !ok {
+			continue
+		}
+		for _, c := range test.WidgetRenderer(w).Objects() {
+			if l, ok := c.(*widget.Label); ok {
+				items[l.Text] = tappable
+			}
+		}
+	}
+	return items
+}
+
+// dialogButton returns the button with the given text of the dialog shown over the given canvas.
+func dialogButton(c fyne.Canvas, text string) *widget.Button {
+	for _, o := range test.LaidOutObjects(c.Overlays().Top()) {
+		if b, ok := o.(*widget.Button); ok && b.Text == text {
+			return b
+		}
+	}
+	return nil
+}
+
+func list(t *testing.T, repo storage.SpaceRepository, u fyne.URI) []string {
+	t.Helper()
+	uris, err := repo.List(u)
+	assert.Nil(t, err)
+	var names []string
+	for _, u := range uris {
+		names = append(names, u.String())
+	}
+	return names
+}
+
+func readAll(t *testing.T, u fyne.URI) string {
+	t.Helper()
+	reader, err := fynestorage.Reader(u)
+	assert.Nil(t, err)
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+// newRepository registers a space repository backed by a fake client, and a memory repository for local files.
+func newRepository(t *testing.T) (*fakeClient, storage.SpaceRepository) {
+	t.Helper()
+	client := &fakeClient{}
+	repo := storage.NewSpaceRepository(client, newNode(Alias))
+	repo.Register()
+	memoryFiles = make(map[string]string)
+	repository.Register(MemoryScheme, &memoryRepository{})
+	return client, repo
+}
+
+type fakeFile struct {
+	entry  *bcgo.BlockEntry
+	meta   *spacego.Meta
+	data   []byte
+	deltas []*spacego.Delta
+}
+
+// fakeClient holds files in memory. Methods which are not needed by the repository are left unimplemented.
+type fakeClient struct {
+	spaceclientgo.SpaceClient
+	// files holds the files of the signed in account
+	files  []*fakeFile
+	addErr error
+}
+
+// add creates a file owned by the signed in account, returning its URI.
+func (c *fakeClient) add(alias, name, mime, data string) storage.FileURI {
+	f := newFakeFile(alias, name, mime, []byte(data))
+	c.files = append(c.files, f)
+	return storage.NewFileURI(f.entry.RecordHash, f.meta)
+}
+
+func (c *fakeClient) Add(node bcgo.Node, listener bcgo.MiningListener, name, mime string, reader io.Reader) (*bcgo.Reference, error) {
+	if c.addErr != nil {
+		return nil, c.addErr
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	f := newFakeFile(node.Account().Alias(), name, mime, data)
+	c.files = append(c.files, f)
+	return &bcgo.Reference{
+		RecordHash: f.entry.RecordHash,
+	}, nil
+}
+
+func (c *fakeClient) AllMetas(node bcgo.Node, callback spacego.MetaCallback) error {
+	return iterateFakeFiles(c.files, callback)
+}
+
+func (c *fakeClient) MetaForHash(node bcgo.Node, hash []byte, callback spacego.MetaCallback) error {
+	if f := findFakeFile(c.files, hash); f != nil {
+		return callback(f.entry, f.meta)
+	}
+	return nil
+}
+
+func (c *fakeClient) ReadFile(node bcgo.Node, hash []byte) (io.Reader, error) {
+	if f := findFakeFile(c.files, hash); f != nil {
+		return bytes.NewReader(f.data), nil
+	}
+	return nil, errors.New("File Not Found")
+}
+
+// WriteFile returns a writer which replaces the content of the file with a single delta when closed.
+func (c *fakeClient) WriteFile(node bcgo.Node, listener bcgo.MiningListener, hash []byte) (io.WriteCloser, error) {
+	f := findFakeFile(c.files, hash)
+	if f == nil {
+		return nil, errors.New("File Not Found")
+	}
+	return &fakeWriteCloser{
+		file: f,
+	}, nil
+}
+
+func (c *fakeClient) SearchMeta(node bcgo.Node, filter spacego.MetaFilter, callback spacego.MetaCallback) error {
+	return iterateFakeFiles(c.files, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		if filter.Filter(m) {
+			return callback(e, m)
+		}
+		return nil
+	})
+}
+
+type fakeWriteCloser struct {
+	bytes.Buffer
+	file *fakeFile
+}
+
+func (w *fakeWriteCloser) Close() error {
+	w.file.deltas = append(w.file.deltas, &spacego.Delta{
+		Delete: uint64(len(w.file.data)),
+		Insert: w.Bytes(),
+	})
+	w.file.data = w.Bytes()
+	return nil
+}
+
+func newFakeFile(alias, name, mime string, data []byte) *fakeFile {
+	hash := sha256.Sum256([]byte(alias + "/" + name + "/" + string(data)))
+	return &fakeFile{
+		entry: &bcgo.BlockEntry{
+			Record: &bcgo.Record{
+				Creator:   alias,
+				Timestamp: 1617235200000000000,
+			},
+			RecordHash: hash[:],
+		},
+		meta: &spacego.Meta{
+			Name: name,
+			Type: mime,
+		},
+		data: data,
+	}
+}
+
+func iterateFakeFiles(files []*fakeFile, callback spacego.MetaCallback) error {
+	for _, f := range files {
+		if err := callback(f.entry, f.meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findFakeFile(files []*fakeFile, hash []byte) *fakeFile {
+	for _, f := range files {
+		if bytes.Equal(f.entry.RecordHash, hash) {
+			return f
+		}
+	}
+	return nil
+}
+
+type fakeAccount struct {
+	bcgo.Account
+	alias string
+}
+
+func (a *fakeAccount) Alias() string {
+	return a.alias
+}
+
+type fakeNode struct {
+	bcgo.Node
+	account bcgo.Account
+}
+
+func newNode(alias string) bcgo.Node {
+	return &fakeNode{
+		account: &fakeAccount{
+			alias: alias,
+		},
+	}
+}
+
+func (n *fakeNode) Account() bcgo.Account {
+	return n.account
+}
+
+// memoryFiles holds the content of memory URIs, standing in for the local file system.
+var memoryFiles map[string]string
+
+func newMemoryURI(t *testing.T, name, content string) fyne.URI {
+	t.Helper()
+	u, err := fynestorage.ParseURI(MemoryScheme + ":///" + name)
+	assert.Nil(t, err)
+	if content != "" {
+		memoryFiles[u.String()] = content
+	}
+	return u
+}
+
+type memoryRepository struct{}
+
+func (r *memoryRepository) CanRead(u fyne.URI) (bool, error) {
+	return r.Exists(u)
+}
+
+func (r *memoryRepository) Copy(src, dest fyne.URI) error {
+	return repository.GenericCopy(src, dest)
+}
+
+func (r *memoryRepository) CanWrite(u fyne.URI) (bool, error) {
+	return true, nil
+}
+
+func (r *memoryRepository) Delete(u fyne.URI) error {
+	delete(memoryFiles, u.String())
+	return nil
+}
+
+func (r *memoryRepository) Destroy(string) {}
+
+func (r *memoryRepository) Exists(u fyne.URI) (bool, error) {
+	_, ok := memoryFiles[u.String()]
+	return ok, nil
+}
+
+func (r *memoryRepository) Reader(u fyne.URI) (fyne.URIReadCloser, error) {
+	content, ok := memoryFiles[u.String()]
+	if !ok {
+		return nil, errors.New("File Not Found")
+	}
+	return &memoryReadCloser{
+		Reader: bytes.NewReader([]byte(content)),
+		uri:    u,
+	}, nil
+}
+
+func (r *memoryRepository) Writer(u fyne.URI) (fyne.URIWriteCloser, error) {
+	return &memoryWriteCloser{
+		uri: u,
+	}, nil
+}
+
+type memoryReadCloser struct {
+	io.Reader
+	uri fyne.URI
+}
+
+func (r *memoryReadCloser) Close() error {
+	return nil
+}
+
+func (r *memoryReadCloser) URI() fyne.URI {
+	return r.uri
+}
+
+type memoryWriteCloser struct {
+	bytes.Buffer
+	uri fyne.URI
+}
+
+func (w *memoryWriteCloser) Close() error {
+	memoryFiles[w.uri.String()] = w.String()
+	return nil
+}
+
+func (w *memoryWriteCloser) URI() fyne.URI {
+	return w.uri
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacego"
+	"encoding/base64"
+	"fyne.io/fyne/v2"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Search times are given in nanoseconds since the Unix epoch, or in RFC 3339 format.
+const (
+	SEARCH_AFTER  = "after"
+	SEARCH_BEFORE = "before"
+	SEARCH_NAME   = "name"
+	SEARCH_TAG    = "tag"
+	SEARCH_TYPE   = "type"
+)
+
+// searchFilter matches files whose name contains any of the given names, and whose type matches any of the given types.
+type searchFilter struct {
+	names []string
+	types []string
+}
+
+func newSearchFilter(query url.Values) *searchFilter {
+	f := &searchFilter{
+		types: query[SEARCH_TYPE],
+	}
+	for _, n := range query[SEARCH_NAME] {
+		f.names = append(f.names, strings.ToLower(n))
+	}
+	return f
+}
+
+func (f *searchFilter) Filter(meta *spacego.Meta) bool {
+	if meta.Type == MIME_TYPE_FOLDER {
+		return false
+	}
+	if len(f.names) > 0 {
+		name := strings.ToLower(meta.Name)
+		found := false
+		for _, n := range f.names {
+			if strings.Contains(name, n) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.types) > 0 {
+		found := false
+		for _, t := range f.types {
+			if matchMimeType(t, meta.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Search triggers the given callback for each file matching the given query.
+func Search(client spaceclientgo.SpaceClient, node bcgo.Node, query url.Values, callback spacego.MetaCallback) error {
+	filter := newSearchFilter(query)
+	cb, err := searchCallback(query, filter, callback)
+	if err != nil {
+		return err
+	}
+	if tags := query[SEARCH_TAG]; len(tags) > 0 {
+		return client.SearchTag(node, spacego.NewTagFilter(tags...), cb)
+	}
+	return client.SearchMeta(node, filter, cb)
+}
+
+// searchCallback wraps the given callback so it is only triggered once for each file matching the filter and the time range of the query.
+func searchCallback(query url.Values, filter *searchFilter, callback spacego.MetaCallback) (spacego.MetaCallback, error) {
+	var after, before uint64
+	if a := query.Get(SEARCH_AFTER); a != "" {
+		t, err := parseTimestamp(a)
+		if err != nil {
+			return nil, err
+		}
+		after = t
+	}
+	if b := query.Get(SEARCH_BEFORE); b != "" {
+		t, err := parseTimestamp(b)
+		if err != nil {
+			return nil, err
+		}
+		before = t
+	}
+	found := make(map[string]bool)
+	return func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		id := base64.RawURLEncoding.EncodeToString(e.RecordHash)
+		if found[id] || !filter.Filter(m) {
+			return nil
+		}
+		if t := e.Record.Timestamp; (after > 0 && t < after) || (before > 0 && t > before) {
+			return nil
+		}
+		found[id] = true
+		return callback(e, m)
+	}, nil
+}
+
+// AllTags triggers the given callback once for each distinct tag value across all files.
+func AllTags(client spaceclientgo.SpaceClient, node bcgo.Node, callback func(string)) error {
+	tags := make(map[string]bool)
+	return client.AllMetas(node, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		return spacego.IterateTags(node, refreshChannel(node, spacego.OpenTagChannel(e.RecordHash)), func(e *bcgo.BlockEntry, t *spacego.Tag) error {
+			if v := t.Value; v != "" && !tags[v] {
+				tags[v] = true
+				callback(v)
+			}
+			return nil
+		})
+	})
+}
+
+// TagCache holds the distinct tag values across all files, so every file's tag channel is only read once per account rather than each time the tags are needed.
+type TagCache struct {
+	lock   sync.Mutex
+	alias  string
+	tags   []string
+	loaded bool
+}
+
+// Tags returns the sorted tag values of the node's account, loading them on first use.
+func (c *TagCache) Tags(client spaceclientgo.SpaceClient, node bcgo.Node) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	alias := node.Account().Alias()
+	if !c.loaded || c.alias != alias {
+		var tags []string
+		if err := AllTags(client, node, func(t string) {
+			tags = append(tags, t)
+		}); err != nil {
+			return nil, err
+		}
+		sort.Strings(tags)
+		c.alias = alias
+		c.tags = tags
+		c.loaded = true
+	}
+	return append([]string(nil), c.tags...), nil
+}
+
+// search returns the files matching the query of the given URI.
+func (r *spaceRepository) search(u *searchURI) ([]fyne.URI, error) {
+	if r.isForeign(u) {
+		return nil, errForeignAlias
+	}
+	var uris []fyne.URI
+	if err := Search(r.client, r.node, u.query, func(e *bcgo.BlockEntry, m *spacego.Meta) error {
+		if u.alias != "" && e.Record.Creator != u.alias {
+			return nil
+		}
+		uris = append(uris, withAlias(NewFileURI(e.RecordHash, m), u.alias))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return uris, nil
+}
+
+// matchMimeType returns true if the given mime type matches the pattern, which may contain a wildcard such as image/*.
+func matchMimeType(pattern, mime string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	switch {
+	case pattern == "", pattern == "*", pattern == "*/*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(mime, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == mime
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacefynego/storage"
+	"aletheiaware.com/spacego"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTagCache(t *testing.T) {
+	client := &countingClient{
+		fakeClient: &fakeClient{},
+	}
+	cache := &storage.TagCache{}
+	node := newNode(Alias)
+	tags, err := cache.Tags(client, node)
+	assert.Nil(t, err)
+	assert.Empty(t, tags)
+	t.Run("Cached", func(t *testing.T) {
+		_, err := cache.Tags(client, node)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, client.count)
+	})
+	t.Run("AccountChanged", func(t *testing.T) {
+		_, err := cache.Tags(client, newNode("bob"))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, client.count)
+	})
+}
+
+// countingClient counts the number of times all files are read.
+type countingClient struct {
+	*fakeClient
+	count int
+}
+
+func (c *countingClient) AllMetas(node bcgo.Node, callback spacego.MetaCallback) error {
+	c.count++
+	return c.fakeClient.AllMetas(node, callback)
+}
@@ -19,13 +19,27 @@ package storage
 import (
 	"aletheiaware.com/spacego"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	SPACE_SCHEME        = "space"
 	SPACE_SCHEME_PREFIX = "space:"
+	MIME_TYPE_FOLDER    = "inode/directory"
+
+	MIME_TYPE_PROTOBUF_DELTA   = "application/x-protobuf; messageType=spacego.Delta"
+	MIME_TYPE_PROTOBUF_META    = "application/x-protobuf; messageType=spacego.Meta"
+	MIME_TYPE_PROTOBUF_PREVIEW = "application/x-protobuf; messageType=spacego.Preview"
+	MIME_TYPE_PROTOBUF_TAG     = "application/x-protobuf; messageType=spacego.Tag"
 )
 
 type SpaceURI interface {
@@ -44,6 +58,19 @@ type FileURI interface {
 	Meta() *spacego.Meta
 }
 
+type VersionURI interface {
+	FileURI
+	AtDelta() []byte
+	// AtTime returns the time of the version in nanoseconds since the Unix epoch, or 0 if the version is identified by a delta.
+	AtTime() uint64
+}
+
+type FolderURI interface {
+	fmt.Stringer
+	fyne.URI
+	Folder() string
+}
+
 type MetaURI interface {
 	SpaceURI
 }
@@ -53,11 +80,51 @@ type PreviewURI interface {
 	PreviewHash() []byte
 }
 
+type SearchURI interface {
+	fmt.Stringer
+	fyne.URI
+	Search() url.Values
+}
+
 type TagURI interface {
 	SpaceURI
 	TagHash() []byte
 }
 
+func NewAliasURI(alias string) fyne.URI {
+	if alias == "" {
+		return RootURI
+	}
+	return &spaceURI{
+		alias: alias,
+	}
+}
+
+func NewDeltaVersionURI(fileHash []byte, meta *spacego.Meta, deltaHash []byte) VersionURI {
+	return &versionURI{
+		fileURI: fileURI{
+			spaceURI: spaceURI{
+				fileHash: fileHash,
+			},
+			meta: meta,
+		},
+		deltaHash: deltaHash,
+	}
+}
+
+// NewTimestampVersionURI returns the URI of the file as it was at the given time, in nanoseconds since the Unix epoch.
+func NewTimestampVersionURI(fileHash []byte, meta *spacego.Meta, timestamp uint64) VersionURI {
+	return &versionURI{
+		fileURI: fileURI{
+			spaceURI: spaceURI{
+				fileHash: fileHash,
+			},
+			meta: meta,
+		},
+		timestamp: timestamp,
+	}
+}
+
 func NewDeltaURI(fileHash, deltaHash []byte) DeltaURI {
 	return &deltaURI{
 		spaceURI: spaceURI{
@@ -76,6 +143,12 @@ func NewFileURI(fileHash []byte, meta *spacego.Meta) FileURI {
 	}
 }
 
+func NewFolderURI(folder string) FolderURI {
+	return &folderURI{
+		folder: folder,
+	}
+}
+
 func NewMetaURI(fileHash []byte) MetaURI {
 	return &metaURI{
 		spaceURI: spaceURI{
@@ -93,6 +166,12 @@ func NewPreviewURI(fileHash, previewHash []byte) PreviewURI {
 	}
 }
 
+func NewSearchURI(query url.Values) SearchURI {
+	return &searchURI{
+		query: query,
+	}
+}
+
 func NewTagURI(fileHash, tagHash []byte) TagURI {
 	return &tagURI{
 		spaceURI: spaceURI{
@@ -103,11 +182,12 @@ func NewTagURI(fileHash, tagHash []byte) TagURI {
 }
 
 type spaceURI struct {
+	alias    string
 	fileHash []byte
 }
 
 func (u *spaceURI) Authority() string {
-	return ""
+	return u.alias
 }
 
 func (u *spaceURI) FileHash() []byte {
@@ -127,7 +207,7 @@ func (u *spaceURI) MimeType() string {
 }
 
 func (u *spaceURI) Name() string {
-	return ""
+	return u.alias
 }
 
 func (u *spaceURI) Path() string {
@@ -143,7 +223,16 @@ func (u *spaceURI) Scheme() string {
 }
 
 func (u *spaceURI) String() string {
-	return SPACE_SCHEME_PREFIX + u.Path()
+	return u.prefix() + u.Path()
+}
+
+// prefix returns the scheme and authority, which is included even when empty as the Fyne file dialog expects URIs to begin with scheme://
+func (u *spaceURI) prefix() string {
+	return SPACE_SCHEME_PREFIX + "//" + u.alias + "/"
+}
+
+func (u *spaceURI) setAlias(alias string) {
+	u.alias = alias
 }
 
 type deltaURI struct {
@@ -156,7 +245,7 @@ func (u *deltaURI) DeltaHash() []byte {
 }
 
 func (u *deltaURI) MimeType() string {
-	return "" // TODO protobuf type
+	return MIME_TYPE_PROTOBUF_DELTA
 }
 
 func (u *deltaURI) Name() string {
@@ -168,7 +257,7 @@ func (u *deltaURI) Path() string {
 }
 
 func (u *deltaURI) String() string {
-	return SPACE_SCHEME_PREFIX + u.Path()
+	return u.prefix() + u.Path()
 }
 
 type fileURI struct {
@@ -176,6 +265,10 @@ type fileURI struct {
 	meta *spacego.Meta
 }
 
+func (u *fileURI) Extension() string {
+	return filepath.Ext(u.meta.Name)
+}
+
 func (u *fileURI) Meta() *spacego.Meta {
 	return u.meta
 }
@@ -185,7 +278,44 @@ func (u *fileURI) MimeType() string {
 }
 
 func (u *fileURI) Name() string {
-	return u.meta.Name
+	return path.Base(u.meta.Name)
+}
+
+func (u *fileURI) Path() string {
+	if len(u.fileHash) == 0 {
+		// File has not been created yet
+		return u.meta.Name
+	}
+	return u.spaceURI.Path()
+}
+
+func (u *fileURI) String() string {
+	return u.prefix() + u.Path()
+}
+
+type folderURI struct {
+	spaceURI
+	folder string
+}
+
+func (u *folderURI) Folder() string {
+	return u.folder
+}
+
+func (u *folderURI) MimeType() string {
+	return MIME_TYPE_FOLDER
+}
+
+func (u *folderURI) Name() string {
+	return path.Base(u.folder)
+}
+
+func (u *folderURI) Path() string {
+	return "/" + u.folder
+}
+
+func (u *folderURI) String() string {
+	return u.prefix() + u.Path()
 }
 
 type metaURI struct {
@@ -193,7 +323,7 @@ type metaURI struct {
 }
 
 func (u *metaURI) MimeType() string {
-	return "" // TODO protobuf type
+	return MIME_TYPE_PROTOBUF_META
 }
 
 func (u *metaURI) Name() string {
@@ -205,16 +335,21 @@ func (u *metaURI) Path() string {
 }
 
 func (u *metaURI) String() string {
-	return SPACE_SCHEME_PREFIX + u.Path()
+	return u.prefix() + u.Path()
 }
 
 type previewURI struct {
 	spaceURI
 	previewHash []byte
+	previewType string
 }
 
 func (u *previewURI) MimeType() string {
-	return "" // TODO protobuf type
+	if u.previewType == "" {
+		// Type is unknown until the preview has been read
+		return MIME_TYPE_PROTOBUF_PREVIEW
+	}
+	return u.previewType
 }
 
 func (u *previewURI) Name() string {
@@ -230,7 +365,29 @@ func (u *previewURI) PreviewHash() []byte {
 }
 
 func (u *previewURI) String() string {
-	return SPACE_SCHEME_PREFIX + u.Path()
+	return u.prefix() + u.Path()
+}
+
+type searchURI struct {
+	spaceURI
+	query url.Values
+}
+
+func (u *searchURI) Name() string {
+	return "Search"
+}
+
+func (u *searchURI) Query() string {
+	return u.query.Encode()
+}
+
+func (u *searchURI) Search() url.Values {
+	return u.query
+}
+
+func (u *searchURI) String() string {
+	// Search the root folder, so the path is empty like that of a folder in the file dialog's breadcrumbs
+	return u.prefix() + "/?" + u.Query()
 }
 
 type tagURI struct {
@@ -239,7 +396,7 @@ type tagURI struct {
 }
 
 func (u *tagURI) MimeType() string {
-	return "" // TODO protobuf type
+	return MIME_TYPE_PROTOBUF_TAG
 }
 
 func (u *tagURI) Name() string {
@@ -251,9 +408,135 @@ func (u *tagURI) Path() string {
 }
 
 func (u *tagURI) String() string {
-	return SPACE_SCHEME_PREFIX + u.Path()
+	return u.prefix() + u.Path()
 }
 
 func (u *tagURI) TagHash() []byte {
 	return u.tagHash
 }
+
+type versionURI struct {
+	fileURI
+	deltaHash []byte
+	timestamp uint64
+}
+
+func (u *versionURI) AtDelta() []byte {
+	return u.deltaHash
+}
+
+func (u *versionURI) AtTime() uint64 {
+	return u.timestamp
+}
+
+func (u *versionURI) Path() string {
+	if len(u.deltaHash) > 0 {
+		return u.spaceURI.Path() + "@" + base64.RawURLEncoding.EncodeToString(u.deltaHash)
+	}
+	return u.spaceURI.Path()
+}
+
+func (u *versionURI) Query() string {
+	if len(u.deltaHash) > 0 {
+		return ""
+	}
+	return "at=" + strconv.FormatUint(u.timestamp, 10)
+}
+
+func (u *versionURI) String() string {
+	if q := u.Query(); q != "" {
+		return u.prefix() + u.Path() + "?" + q
+	}
+	return u.prefix() + u.Path()
+}
+
+var errInvalidTimestamp = errors.New("Invalid Timestamp")
+
+// timestampKeys are the query keys whose values are times.
+var timestampKeys = map[string]bool{
+	"at":          true,
+	SEARCH_AFTER:  true,
+	SEARCH_BEFORE: true,
+}
+
+// parseQuery parses the query of a URI, keeping any "+" in times rather than decoding it as a space, so RFC 3339 offsets such as +02:00 can be given.
+func parseQuery(raw string) (url.Values, error) {
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+	times := make(url.Values)
+	for _, p := range strings.Split(raw, "&") {
+		key, value := p, ""
+		if i := strings.Index(p, "="); i >= 0 {
+			key, value = p[:i], p[i+1:]
+		}
+		if k, err := url.QueryUnescape(key); err != nil || !timestampKeys[k] {
+			continue
+		} else {
+			key = k
+		}
+		v, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		times[key] = append(times[key], v)
+	}
+	for k, v := range times {
+		query[k] = v
+	}
+	return query, nil
+}
+
+// parseTimestamp parses a time given in nanoseconds since the Unix epoch, or in RFC 3339 format, such as 2021-04-01T00:00:00Z.
+func parseTimestamp(s string) (uint64, error) {
+	if t, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if t == 0 {
+			return 0, fmt.Errorf("Could not parse timestamp %s: %w", s, errInvalidTimestamp)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse timestamp %s: %w", s, errInvalidTimestamp)
+	}
+	if t.UnixNano() <= 0 {
+		return 0, fmt.Errorf("Could not parse timestamp %s: %w", s, errInvalidTimestamp)
+	}
+	return uint64(t.UnixNano()), nil
+}
+
+// mimeTypeForName guesses the mime type of a file from the extension of its name.
+func mimeTypeForName(name string) string {
+	m := mime.TypeByExtension(filepath.Ext(name))
+	if m == "" {
+		return "application/octet-stream"
+	}
+	// Remove any parameters
+	if i := strings.Index(m, ";"); i >= 0 {
+		m = m[:i]
+	}
+	return m
+}
+
+// isRoot returns true if the given URI lists all files, or all files owned by an alias.
+func isRoot(u fyne.URI) bool {
+	s, ok := u.(*spaceURI)
+	return ok && len(s.fileHash) == 0
+}
+
+// parentFolder returns the URI of the folder containing the file or folder with the given name.
+func parentFolder(alias, name string) fyne.URI {
+	if i := strings.LastIndex(name, "/"); i > 0 {
+		return withAlias(NewFolderURI(name[:i]), alias)
+	}
+	return NewAliasURI(alias)
+}
+
+// withAlias qualifies the given URI with the alias that owns it.
+func withAlias(u fyne.URI, alias string) fyne.URI {
+	if a, ok := u.(interface{ setAlias(string) }); ok && alias != "" {
+		a.setAlias(alias)
+	}
+	return u
+}
@@ -5,6 +5,7 @@ import (
 	"aletheiaware.com/spacego"
 	"encoding/base64"
 	"github.com/stretchr/testify/assert"
+	"net/url"
 	"testing"
 )
 
@@ -16,21 +17,62 @@ const (
 	PreviewHash = "ijkl9012"
 	TagHash     = "mnop3456"
 
-	DeltaURI   = "space:abcd1234/delta/efgh5678"
-	FileURI    = "space:abcd1234"
-	MetaURI    = "space:abcd1234/meta"
-	PreviewURI = "space:abcd1234/preview/ijkl9012"
-	TagURI     = "space:abcd1234/tag/mnop3456"
+	FolderName = "Test/Notes"
+
+	DeltaURI   = "space:///abcd1234/delta/efgh5678"
+	FileURI    = "space:///abcd1234"
+	FolderURI  = "space:////Test/Notes"
+	MetaURI    = "space:///abcd1234/meta"
+	PreviewURI = "space:///abcd1234/preview/ijkl9012"
+	TagURI     = "space:///abcd1234/tag/mnop3456"
+
+	AliasURI            = "space://alice/"
+	DeltaVersionURI     = "space:///abcd1234@efgh5678"
+	SearchURI           = "space:////?name=report&tag=invoice&type=image%2F%2A"
+	TimestampVersionURI = "space:///abcd1234?at=1617235200000000000"
 )
 
+func TestURI_AliasURI_String(t *testing.T) {
+	assert.Equal(t, AliasURI, storage.NewAliasURI("alice").String())
+	assert.Equal(t, "space:///", storage.NewAliasURI("").String())
+}
+
+func TestURI_AliasURI_Authority(t *testing.T) {
+	assert.Equal(t, "alice", storage.NewAliasURI("alice").Authority())
+}
+
+func TestURI_DeltaURI_MimeType(t *testing.T) {
+	assert.Equal(t, storage.MIME_TYPE_PROTOBUF_DELTA, newDeltaURI(t).MimeType())
+}
+
 func TestURI_DeltaURI_String(t *testing.T) {
 	assert.Equal(t, DeltaURI, newDeltaURI(t).String())
 }
 
+func TestURI_FileURI_Extension(t *testing.T) {
+	assert.Equal(t, ".txt", newFileURI(t).Extension())
+}
+
+func TestURI_FileURI_MimeType(t *testing.T) {
+	assert.Equal(t, FileType, newFileURI(t).MimeType())
+}
+
 func TestURI_FileURI_String(t *testing.T) {
 	assert.Equal(t, FileURI, newFileURI(t).String())
 }
 
+func TestURI_FolderURI_String(t *testing.T) {
+	assert.Equal(t, FolderURI, storage.NewFolderURI(FolderName).String())
+}
+
+func TestURI_FolderURI_Name(t *testing.T) {
+	assert.Equal(t, "Notes", storage.NewFolderURI(FolderName).Name())
+}
+
+func TestURI_MetaURI_MimeType(t *testing.T) {
+	assert.Equal(t, storage.MIME_TYPE_PROTOBUF_META, newMetaURI(t).MimeType())
+}
+
 func TestURI_MetaURI_String(t *testing.T) {
 	assert.Equal(t, MetaURI, newMetaURI(t).String())
 }
@@ -39,10 +81,33 @@ func TestURI_PreviewURI_String(t *testing.T) {
 	assert.Equal(t, PreviewURI, newPreviewURI(t).String())
 }
 
+func TestURI_SearchURI_String(t *testing.T) {
+	assert.Equal(t, SearchURI, storage.NewSearchURI(url.Values{
+		storage.SEARCH_NAME: []string{"report"},
+		storage.SEARCH_TAG:  []string{"invoice"},
+		storage.SEARCH_TYPE: []string{"image/*"},
+	}).String())
+}
+
+func TestURI_TagURI_MimeType(t *testing.T) {
+	assert.Equal(t, storage.MIME_TYPE_PROTOBUF_TAG, newTagURI(t).MimeType())
+}
+
 func TestURI_TagURI_String(t *testing.T) {
 	assert.Equal(t, TagURI, newTagURI(t).String())
 }
 
+func TestURI_VersionURI_String(t *testing.T) {
+	t.Run("Delta", func(t *testing.T) {
+		hash, err := base64.RawURLEncoding.DecodeString(DeltaHash)
+		assert.Nil(t, err)
+		assert.Equal(t, DeltaVersionURI, storage.NewDeltaVersionURI(fileHash(t), newFileURI(t).Meta(), hash).String())
+	})
+	t.Run("Timestamp", func(t *testing.T) {
+		assert.Equal(t, TimestampVersionURI, storage.NewTimestampVersionURI(fileHash(t), newFileURI(t).Meta(), 1617235200000000000).String())
+	})
+}
+
 func fileHash(t *testing.T) []byte {
 	hash, err := base64.RawURLEncoding.DecodeString(FileHash)
 	assert.Nil(t, err)
@@ -19,6 +19,7 @@ package ui
 import (
 	"aletheiaware.com/bcgo"
 	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacefynego/storage"
 	"aletheiaware.com/spacego"
 	"encoding/base64"
 	"fyne.io/fyne/v2"
@@ -101,6 +102,10 @@ func NewMetaList(callback func(id string, timestamp uint64, meta *spacego.Meta))
 }
 
 func (l *MetaList) Add(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+	if meta.Type == storage.MIME_TYPE_FOLDER {
+		// Folders are only listed by the space repository
+		return nil
+	}
 	id := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
 	if _, ok := l.metas[id]; !ok {
 		l.metas[id] = meta
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ui
+
+import (
+	"aletheiaware.com/spacefynego/storage"
+	"aletheiaware.com/spacego"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ResultList shows ranked full-text search results along with a snippet of each file's matching content.
+type ResultList struct {
+	widget.List
+	results []*storage.IndexResult
+}
+
+func NewResultList(callback func(id string, timestamp uint64, meta *spacego.Meta)) *ResultList {
+	l := &ResultList{
+		List: widget.List{
+			CreateItem: func() fyne.CanvasObject {
+				return container.NewVBox(
+					&widget.Label{
+						TextStyle: fyne.TextStyle{
+							Bold: true,
+						},
+						Wrapping: fyne.TextTruncate,
+					},
+					&widget.Label{
+						TextStyle: fyne.TextStyle{
+							Italic: true,
+						},
+						Wrapping: fyne.TextTruncate,
+					},
+				)
+			},
+		},
+	}
+	l.Length = func() int {
+		return len(l.results)
+	}
+	l.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
+		if id < 0 || id >= len(l.results) {
+			return
+		}
+		r := l.results[id]
+		name := r.Meta.Name
+		if name == "" {
+			name = "(untitled)"
+		}
+		items := item.(*fyne.Container).Objects
+		items[0].(*widget.Label).SetText(name)
+		items[1].(*widget.Label).SetText(r.Snippet)
+	}
+	l.OnSelected = func(id widget.ListItemID) {
+		if id < 0 || id >= len(l.results) {
+			return
+		}
+		if r := l.results[id]; callback != nil {
+			callback(r.ID, r.Timestamp, r.Meta)
+		}
+		l.Unselect(id) // TODO FIXME Hack
+	}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+func (l *ResultList) Clear() {
+	l.results = nil
+	l.Refresh()
+}
+
+func (l *ResultList) SetResults(results []*storage.IndexResult) {
+	l.results = results
+	l.Refresh()
+}
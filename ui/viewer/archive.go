@@ -0,0 +1,761 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"aletheiaware.com/bcgo"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"io"
+	"io/ioutil"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MIME_TYPE_APPLICATION_ZIP  = "application/zip"
+	MIME_TYPE_APPLICATION_TAR  = "application/x-tar"
+	MIME_TYPE_APPLICATION_GZIP = "application/gzip"
+)
+
+func init() {
+	generator := func() (Viewer, error) {
+		return NewArchiveViewer(), nil
+	}
+	RegisterViewer(MIME_TYPE_APPLICATION_ZIP, "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer("application/x-zip-compressed", "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer(MIME_TYPE_APPLICATION_TAR, "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer(MIME_TYPE_APPLICATION_GZIP, "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer("application/x-gzip", "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer("application/x-compressed-tar", "Archive", PRIORITY_DEFAULT, generator)
+	RegisterViewer("application/x-gtar", "Archive", PRIORITY_DEFAULT, generator)
+	RegisterExtension(".zip", MIME_TYPE_APPLICATION_ZIP)
+	RegisterExtension(".tar", MIME_TYPE_APPLICATION_TAR)
+	RegisterExtension(".gz", MIME_TYPE_APPLICATION_GZIP)
+	RegisterExtension(".tgz", MIME_TYPE_APPLICATION_GZIP)
+}
+
+// Limits on uncompressed content, which can be far larger than the archive itself
+var (
+	// archiveMaxEntryBytes limits the content of each entry read when it is opened or extracted
+	archiveMaxEntryBytes int64 = 256 * 1024 * 1024
+	// archiveMaxBytes limits the content of the whole archive read when listing a compressed tar, or when extracting
+	archiveMaxBytes int64 = 4 * 1024 * 1024 * 1024
+)
+
+var (
+	errEntryTooLarge   = errors.New("Entry is too large when uncompressed")
+	errArchiveTooLarge = errors.New("Archive is too large when uncompressed")
+	errFileExists      = errors.New("File already exists")
+)
+
+// archiveEntry is a file or directory within an archive.
+type archiveEntry struct {
+	name     string
+	size     uint64
+	modified time.Time
+	dir      bool
+	open     func() (io.ReadCloser, error)
+}
+
+// ArchiveViewer lists the entries of zip, tar, and gzipped tar archives, which can be opened or extracted.
+type ArchiveViewer struct {
+	widget.BaseWidget
+	entries []*archiveEntry
+	// checked holds the indices of entries selected for extraction
+	checked map[int]bool
+	lock    sync.Mutex
+}
+
+func NewArchiveViewer() *ArchiveViewer {
+	v := &ArchiveViewer{
+		checked: make(map[int]bool),
+	}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *ArchiveViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &archiveViewerRenderer{
+		viewer: v,
+		count:  widget.NewLabel(""),
+	}
+	r.list = &widget.List{
+		Length: func() int {
+			v.lock.Lock()
+			defer v.lock.Unlock()
+			return len(v.entries)
+		},
+		CreateItem: func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, container.NewGridWithColumns(3,
+				&widget.Label{
+					TextStyle: fyne.TextStyle{
+						Bold: true,
+					},
+					Wrapping: fyne.TextTruncate,
+				},
+				&widget.Label{
+					Alignment: fyne.TextAlignTrailing,
+					TextStyle: fyne.TextStyle{
+						Monospace: true,
+					},
+					Wrapping: fyne.TextTruncate,
+				},
+				&widget.Label{
+					Alignment: fyne.TextAlignTrailing,
+					TextStyle: fyne.TextStyle{
+						Monospace: true,
+					},
+					Wrapping: fyne.TextTruncate,
+				},
+			))
+		},
+		UpdateItem: v.updateItem,
+	}
+	r.list.OnSelected = func(id widget.ListItemID) {
+		go v.Open(id)
+		r.list.Unselect(id) // TODO FIXME Hack
+	}
+	r.list.ExtendBaseWidget(r.list)
+	r.all = widget.NewCheck("", v.SetAllChecked)
+	extract := widget.NewButtonWithIcon("Extract", theme.DownloadIcon(), v.showExtract)
+	r.content = container.NewBorder(container.NewBorder(nil, nil, r.all, extract, r.count), nil, nil, nil, r.list)
+	r.objects = []fyne.CanvasObject{r.content}
+	r.Refresh()
+	return r
+}
+
+func (v *ArchiveViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+// SetSource lists the entries of the archive, the content of an entry is only read when it is opened or extracted.
+func (v *ArchiveViewer) SetSource(source io.Reader) error {
+	random, size, err := randomAccess(source)
+	if err != nil {
+		return err
+	}
+	entries, err := readArchive(random, size)
+	if err != nil {
+		return err
+	}
+	v.lock.Lock()
+	v.entries = entries
+	v.checked = make(map[int]bool)
+	v.lock.Unlock()
+	v.Refresh()
+	return nil
+}
+
+// SetChecked selects or deselects the entry at the given index for extraction.
+func (v *ArchiveViewer) SetChecked(index int, checked bool) {
+	v.lock.Lock()
+	if checked {
+		v.checked[index] = true
+	} else {
+		delete(v.checked, index)
+	}
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// SetAllChecked selects or deselects all entries for extraction.
+func (v *ArchiveViewer) SetAllChecked(checked bool) {
+	v.lock.Lock()
+	v.checked = make(map[int]bool)
+	if checked {
+		for i := range v.entries {
+			v.checked[i] = true
+		}
+	}
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// Open shows the entry at the given index in a new window, using the viewer registered for its type.
+func (v *ArchiveViewer) Open(index int) {
+	v.lock.Lock()
+	if index < 0 || index >= len(v.entries) {
+		v.lock.Unlock()
+		return
+	}
+	entry := v.entries[index]
+	v.lock.Unlock()
+	if entry.dir {
+		return
+	}
+	parent := windowFor(v)
+	reader, err := entry.open()
+	if err != nil {
+		v.showError(parent, err)
+		return
+	}
+	// Read the content before closing the entry, as viewers may continue to read from their source
+	content, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		v.showError(parent, err)
+		return
+	}
+
+	view, err := ForMime(entryMime(entry.name))
+	if err != nil || view == nil {
+		view = NewHexViewer()
+	}
+	if err := view.SetSource(bytes.NewReader(content)); err != nil {
+		v.showError(parent, err)
+		return
+	}
+
+	window := fyne.CurrentApp().NewWindow(entry.name)
+	window.SetContent(view)
+	if player, ok := view.(Player); ok {
+		window.SetOnClosed(player.Stop)
+	}
+	if parent != nil {
+		window.Resize(parent.Canvas().Size())
+	}
+	window.CenterOnScreen()
+	window.Show()
+}
+
+// Extract writes the checked entries, or all entries if none are checked, into the given folder. Existing files are only replaced if overwrite is true, otherwise nothing is extracted.
+func (v *ArchiveViewer) Extract(folder fyne.URI, overwrite bool) (int, error) {
+	entries := v.extracting()
+	if !overwrite {
+		existing, err := existingEntries(folder, entries)
+		if err != nil {
+			return 0, err
+		}
+		if len(existing) > 0 {
+			return 0, fmt.Errorf("Could not extract %s: %w", existing[0], errFileExists)
+		}
+	}
+	count := 0
+	remaining := archiveMaxBytes
+	for _, e := range entries {
+		n, err := extractEntry(folder, e, remaining, overwrite)
+		if err != nil {
+			return count, fmt.Errorf("Could not extract %s: %w", e.name, err)
+		}
+		remaining -= n
+		if !e.dir {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Existing returns the names of the files Extract would replace in the given folder.
+func (v *ArchiveViewer) Existing(folder fyne.URI) ([]string, error) {
+	return existingEntries(folder, v.extracting())
+}
+
+// extracting returns the checked entries, or all entries if none are checked.
+func (v *ArchiveViewer) extracting() []*archiveEntry {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	var entries []*archiveEntry
+	for i, e := range v.entries {
+		if len(v.checked) == 0 || v.checked[i] {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (v *ArchiveViewer) showExtract() {
+	window := windowFor(v)
+	if window == nil {
+		return
+	}
+	dialog.ShowFolderOpen(func(folder fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if folder == nil {
+			return
+		}
+		existing, err := v.Existing(folder)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if len(existing) == 0 {
+			v.extract(folder, false, window)
+			return
+		}
+		dialog.ShowConfirm("Overwrite Files", fmt.Sprintf("%d of the files already exist in %s, overwrite them?", len(existing), folder.Name()), func(overwrite bool) {
+			if overwrite {
+				v.extract(folder, true, window)
+			}
+		}, window)
+	}, window)
+}
+
+func (v *ArchiveViewer) extract(folder fyne.URI, overwrite bool, window fyne.Window) {
+	count, err := v.Extract(folder, overwrite)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	dialog.ShowInformation("Extracted", fmt.Sprintf("Extracted %d files to %s", count, folder.Name()), window)
+}
+
+func (v *ArchiveViewer) showError(window fyne.Window, err error) {
+	if window == nil {
+		fyne.LogError("Failed to open entry", err)
+		return
+	}
+	dialog.ShowError(err, window)
+}
+
+func (v *ArchiveViewer) updateItem(id widget.ListItemID, item fyne.CanvasObject) {
+	v.lock.Lock()
+	if id < 0 || id >= len(v.entries) {
+		v.lock.Unlock()
+		return
+	}
+	entry := v.entries[id]
+	checked := v.checked[id]
+	v.lock.Unlock()
+	objects := item.(*fyne.Container).Objects
+	var check *widget.Check
+	var labels []fyne.CanvasObject
+	for _, o := range objects {
+		switch o := o.(type) {
+		case *widget.Check:
+			check = o
+		case *fyne.Container:
+			labels = o.Objects
+		}
+	}
+	// Clear the callback so updating the template doesn't change the selection
+	check.OnChanged = nil
+	check.SetChecked(checked)
+	check.OnChanged = func(checked bool) {
+		v.SetChecked(id, checked)
+	}
+	size := bcgo.BinarySizeToString(entry.size)
+	if entry.dir {
+		size = ""
+	}
+	date := ""
+	if !entry.modified.IsZero() {
+		date = bcgo.TimestampToString(uint64(entry.modified.UnixNano()))
+	}
+	labels[0].(*widget.Label).SetText(entry.name)
+	labels[1].(*widget.Label).SetText(size)
+	labels[2].(*widget.Label).SetText(date)
+}
+
+type archiveViewerRenderer struct {
+	viewer  *ArchiveViewer
+	list    *widget.List
+	all     *widget.Check
+	count   *widget.Label
+	content *fyne.Container
+	objects []fyne.CanvasObject
+}
+
+func (r *archiveViewerRenderer) Destroy() {}
+
+func (r *archiveViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *archiveViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *archiveViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *archiveViewerRenderer) Refresh() {
+	v := r.viewer
+	v.lock.Lock()
+	total := len(v.entries)
+	checked := len(v.checked)
+	v.lock.Unlock()
+	status := fmt.Sprintf("%d entries", total)
+	if checked > 0 {
+		status = fmt.Sprintf("%d of %d entries selected", checked, total)
+	}
+	r.count.SetText(status)
+	r.all.Checked = total > 0 && checked == total
+	r.all.Refresh()
+	r.list.Refresh()
+}
+
+// randomAccess returns the source if it supports random access, otherwise the source is read into memory.
+func randomAccess(source io.Reader) (io.ReaderAt, int64, error) {
+	if r, ok := source.(io.ReaderAt); ok {
+		if s, ok := source.(io.Seeker); ok {
+			if end, err := s.Seek(0, io.SeekEnd); err == nil {
+				return r, end, nil
+			}
+		}
+	}
+	data, err := ioutil.ReadAll(source)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// readArchive lists the entries of a zip, tar, or gzipped archive, detected by its content.
+func readArchive(r io.ReaderAt, size int64) ([]*archiveEntry, error) {
+	magic := make([]byte, 4)
+	n, _ := r.ReadAt(magic, 0)
+	magic = magic[:n]
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return readZip(r, size)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return readGzip(r, size)
+	}
+	return readTar(func() (io.Reader, error) {
+		return io.NewSectionReader(r, 0, size), nil
+	})
+}
+
+func readZip(r io.ReaderAt, size int64) ([]*archiveEntry, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read zip: %w", err)
+	}
+	var entries []*archiveEntry
+	for _, f := range reader.File {
+		entries = append(entries, &archiveEntry{
+			name:     f.Name,
+			size:     f.UncompressedSize64,
+			modified: f.Modified,
+			dir:      f.FileInfo().IsDir(),
+			open:     zipOpener(f),
+		})
+	}
+	return entries, nil
+}
+
+// zipOpener returns a function which opens the content of the file.
+func zipOpener(f *zip.File) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if f.UncompressedSize64 > uint64(archiveMaxEntryBytes) {
+			return nil, errEntryTooLarge
+		}
+		reader, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return limitEntry(reader), nil
+	}
+}
+
+// readTar lists the entries of a tar from their headers, skipping their content, which is read from a new reader of the source when the entry is opened.
+func readTar(source func() (io.Reader, error)) ([]*archiveEntry, error) {
+	r, err := source()
+	if err != nil {
+		return nil, err
+	}
+	reader := tar.NewReader(r)
+	var entries []*archiveEntry
+	for index := 0; ; index++ {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Could not read tar: %w", err)
+		}
+		entry := &archiveEntry{
+			name:     header.Name,
+			size:     uint64(header.Size),
+			modified: header.ModTime,
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.dir = true
+		case tar.TypeReg, tar.TypeRegA:
+			entry.open = tarOpener(source, index, header.Size)
+		default:
+			// Skip links, devices, etc
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// tarOpener returns a function which opens the content of the entry with the given index, of all headers in the tar.
+func tarOpener(source func() (io.Reader, error), index int, size int64) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if size > archiveMaxEntryBytes {
+			return nil, errEntryTooLarge
+		}
+		r, err := source()
+		if err != nil {
+			return nil, err
+		}
+		reader := tar.NewReader(r)
+		for i := 0; i <= index; i++ {
+			if _, err := reader.Next(); err != nil {
+				return nil, fmt.Errorf("Could not read tar: %w", err)
+			}
+		}
+		return limitEntry(ioutil.NopCloser(reader)), nil
+	}
+}
+
+// readGzip lists the entries of a gzipped tar, or the single compressed file if it doesn't contain a tar.
+func readGzip(r io.ReaderAt, size int64) ([]*archiveEntry, error) {
+	// Content is decompressed as it is read, failing if the archive is too large
+	source := func() (io.Reader, error) {
+		reader, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("Could not read gzip: %w", err)
+		}
+		return &archiveLimitReader{
+			reader:    reader,
+			remaining: archiveMaxBytes,
+			err:       errArchiveTooLarge,
+		}, nil
+	}
+	reader, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read gzip: %w", err)
+	}
+	if _, err := tar.NewReader(reader).Next(); err == nil {
+		return readTar(source)
+	}
+	name := reader.Name
+	if name == "" {
+		name = "(uncompressed)"
+	}
+	// Gzip ends with the size of the uncompressed content, modulo 2^32
+	var uncompressed uint64
+	trailer := make([]byte, 4)
+	if _, err := r.ReadAt(trailer, size-4); err == nil {
+		uncompressed = uint64(binary.LittleEndian.Uint32(trailer))
+	}
+	return []*archiveEntry{
+		{
+			name:     name,
+			size:     uncompressed,
+			modified: reader.ModTime,
+			open: func() (io.ReadCloser, error) {
+				reader, err := source()
+				if err != nil {
+					return nil, err
+				}
+				return limitEntry(ioutil.NopCloser(reader)), nil
+			},
+		},
+	}, nil
+}
+
+// archiveLimitReader fails once more than the remaining bytes are read, rather than silently truncating the content like io.LimitReader.
+type archiveLimitReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+func (r *archiveLimitReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.err
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err
+	}
+	return n, err
+}
+
+// limitEntry fails reads of the entry beyond archiveMaxEntryBytes.
+func limitEntry(reader io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: &archiveLimitReader{
+			reader:    reader,
+			remaining: archiveMaxEntryBytes,
+			err:       errEntryTooLarge,
+		},
+		Closer: reader,
+	}
+}
+
+// entryPath returns the components of the entry's name, which cannot escape the folder it is extracted to, or nil if it has no name.
+func entryPath(name string) []string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+// existingEntries returns the names of the entries, other than folders, which already exist in the folder.
+func existingEntries(folder fyne.URI, entries []*archiveEntry) ([]string, error) {
+	var existing []string
+	for _, e := range entries {
+		components := entryPath(e.name)
+		if e.dir || len(components) == 0 {
+			continue
+		}
+		uri := folder
+		for _, c := range components {
+			child, err := storage.Child(uri, c)
+			if err != nil {
+				return nil, err
+			}
+			uri = child
+		}
+		exists, err := storage.Exists(uri)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			existing = append(existing, e.name)
+		}
+	}
+	return existing, nil
+}
+
+// extractEntry writes the entry into the folder, creating any parent folders, and returns the number of bytes written, which cannot exceed the limit.
+// An existing file is only replaced if overwrite is true, and a partially written file is removed.
+func extractEntry(folder fyne.URI, entry *archiveEntry, limit int64, overwrite bool) (int64, error) {
+	components := entryPath(entry.name)
+	if len(components) == 0 {
+		return 0, nil
+	}
+	last := len(components) - 1
+	if entry.dir {
+		// Create the folder itself
+		last++
+	}
+	uri := folder
+	for i, c := range components {
+		child, err := storage.Child(uri, c)
+		if err != nil {
+			return 0, err
+		}
+		uri = child
+		if i >= last {
+			break
+		}
+		exists, err := storage.Exists(uri)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			if err := storage.CreateListable(uri); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if entry.dir {
+		return 0, nil
+	}
+	if entry.open == nil {
+		return 0, errors.New("Entry has no content")
+	}
+	reader, err := entry.open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	if !overwrite {
+		// Check again in case the file was created since the entries were checked
+		exists, err := storage.Exists(uri)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, errFileExists
+		}
+	}
+	writer, err := storage.Writer(uri)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(writer, &archiveLimitReader{
+		reader:    reader,
+		remaining: limit,
+		err:       errArchiveTooLarge,
+	})
+	if e := writer.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		// Don't leave a partial file
+		storage.Delete(uri)
+		return n, err
+	}
+	return n, nil
+}
+
+// entryMime returns the mime type of an entry from the extension of its name.
+func entryMime(name string) string {
+	if m := MimeForName(name); m != "" {
+		return m
+	}
+	if m := mime.TypeByExtension(path.Ext(name)); m != "" {
+		// Remove parameters, such as charset
+		return strings.TrimSpace(strings.Split(m, ";")[0])
+	}
+	return MIME_TYPE_APPLICATION_OCTET_STREAM
+}
+
+// windowFor returns the window showing the given object, or nil if it is not shown.
+func windowFor(object fyne.CanvasObject) fyne.Window {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return nil
+	}
+	c := app.Driver().CanvasForObject(object)
+	if c == nil {
+		return nil
+	}
+	for _, w := range app.Driver().AllWindows() {
+		if w.Canvas() == c {
+			return w
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveFile is a file, or a folder if the content is nil, to add to a test archive.
+type archiveFile struct {
+	name    string
+	content []byte
+}
+
+var archiveFiles = []archiveFile{
+	{"docs/", nil},
+	{"docs/readme.txt", []byte("Hello World")},
+	{"data.bin", bytes.Repeat([]byte{0xff}, 4096)},
+}
+
+func buildZip(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, f := range files {
+		w, err := writer.Create(f.name)
+		assert.Nil(t, err)
+		_, err = w.Write(f.content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func buildTar(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, f := range files {
+		header := &tar.Header{
+			Name:     f.name,
+			Mode:     0644,
+			Size:     int64(len(f.content)),
+			Typeflag: tar.TypeReg,
+		}
+		if f.content == nil {
+			header.Typeflag = tar.TypeDir
+		}
+		assert.Nil(t, writer.WriteHeader(header))
+		_, err := writer.Write(f.content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func buildGzip(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Name = name
+	_, err := writer.Write(data)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	return buffer.Bytes()
+}
+
+// readEntry returns the content of the entry.
+func readEntry(t *testing.T, entry *archiveEntry) ([]byte, error) {
+	t.Helper()
+	reader, err := entry.open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func TestReadArchive(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"Zip":     buildZip(t, archiveFiles),
+		"Tar":     buildTar(t, archiveFiles),
+		"TarGzip": buildGzip(t, "", buildTar(t, archiveFiles)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			entries, err := readArchive(bytes.NewReader(data), int64(len(data)))
+			assert.Nil(t, err)
+			assert.Equal(t, len(archiveFiles), len(entries))
+			// Open the entries out of order, as each is read independently
+			for i := len(entries) - 1; i >= 0; i-- {
+				e, f := entries[i], archiveFiles[i]
+				assert.Equal(t, f.name, e.name)
+				assert.Equal(t, f.content == nil, e.dir)
+				if e.dir {
+					continue
+				}
+				assert.Equal(t, uint64(len(f.content)), e.size)
+				content, err := readEntry(t, e)
+				assert.Nil(t, err)
+				assert.Equal(t, f.content, content)
+			}
+		})
+	}
+}
+
+func TestReadArchive_Gzip(t *testing.T) {
+	data := buildGzip(t, "notes.txt", []byte("Hello World"))
+	entries, err := readArchive(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "notes.txt", entries[0].name)
+	assert.Equal(t, uint64(11), entries[0].size)
+	content, err := readEntry(t, entries[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello World", string(content))
+}
+
+func TestReadArchive_EntryTooLarge(t *testing.T) {
+	defer func(max int64) {
+		archiveMaxEntryBytes = max
+	}(archiveMaxEntryBytes)
+	archiveMaxEntryBytes = 1024
+	for name, data := range map[string][]byte{
+		"Zip":     buildZip(t, archiveFiles),
+		"Tar":     buildTar(t, archiveFiles),
+		"TarGzip": buildGzip(t, "", buildTar(t, archiveFiles)),
+		"Gzip":    buildGzip(t, "data.bin", archiveFiles[2].content),
+	} {
+		t.Run(name, func(t *testing.T) {
+			entries, err := readArchive(bytes.NewReader(data), int64(len(data)))
+			assert.Nil(t, err)
+			_, err = readEntry(t, entries[len(entries)-1])
+			assert.True(t, errors.Is(err, errEntryTooLarge), err)
+		})
+	}
+}
+
+func TestReadArchive_ArchiveTooLarge(t *testing.T) {
+	defer func(max int64) {
+		archiveMaxBytes = max
+	}(archiveMaxBytes)
+	archiveMaxBytes = 1024
+	// Compresses to a fraction of its size, so listing must stop decompressing at the limit
+	data := buildGzip(t, "", buildTar(t, []archiveFile{
+		{"zeros", make([]byte, 1024*1024)},
+	}))
+	_, err := readArchive(bytes.NewReader(data), int64(len(data)))
+	assert.True(t, errors.Is(err, errArchiveTooLarge), err)
+}
+
+func TestArchiveViewer_Extract(t *testing.T) {
+	test.NewApp()
+	folder := storage.NewFileURI(t.TempDir())
+	v := NewArchiveViewer()
+	assert.Nil(t, v.SetSource(bytes.NewReader(buildTar(t, append(archiveFiles, archiveFile{"../escape.txt", []byte("Escape")})))))
+
+	count, err := v.Extract(folder, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	for _, f := range []archiveFile{
+		archiveFiles[1],
+		archiveFiles[2],
+		{"escape.txt", []byte("Escape")},
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(folder.Path(), filepath.FromSlash(f.name)))
+		assert.Nil(t, err)
+		assert.Equal(t, f.content, content)
+	}
+
+	// Existing files are not replaced without permission
+	existing, err := v.Existing(folder)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"docs/readme.txt", "data.bin", "../escape.txt"}, existing)
+	path := filepath.Join(folder.Path(), "docs", "readme.txt")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("Changed"), 0644))
+	count, err = v.Extract(folder, false)
+	assert.True(t, errors.Is(err, errFileExists), err)
+	assert.Equal(t, 0, count)
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "Changed", string(content))
+
+	count, err = v.Extract(folder, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	content, err = ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello World", string(content))
+}
+
+func TestArchiveViewer_Extract_Checked(t *testing.T) {
+	test.NewApp()
+	folder := storage.NewFileURI(t.TempDir())
+	v := NewArchiveViewer()
+	assert.Nil(t, v.SetSource(bytes.NewReader(buildZip(t, archiveFiles))))
+	v.SetChecked(2, true)
+
+	count, err := v.Extract(folder, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	_, err = ioutil.ReadFile(filepath.Join(folder.Path(), "data.bin"))
+	assert.Nil(t, err)
+	_, err = ioutil.ReadFile(filepath.Join(folder.Path(), "docs", "readme.txt"))
+	assert.NotNil(t, err)
+}
+
+func TestExtractEntry(t *testing.T) {
+	test.NewApp()
+	entry := func(content string) *archiveEntry {
+		return &archiveEntry{
+			name: "docs/readme.txt",
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader(content)), nil
+			},
+		}
+	}
+	t.Run("TooLarge", func(t *testing.T) {
+		folder := storage.NewFileURI(t.TempDir())
+		_, err := extractEntry(folder, entry("Hello World"), 5, false)
+		assert.True(t, errors.Is(err, errArchiveTooLarge), err)
+		// Partially written file is removed
+		_, err = os.Stat(filepath.Join(folder.Path(), "docs", "readme.txt"))
+		assert.True(t, os.IsNotExist(err), err)
+	})
+	t.Run("Exists", func(t *testing.T) {
+		folder := storage.NewFileURI(t.TempDir())
+		path := filepath.Join(folder.Path(), "docs", "readme.txt")
+		assert.Nil(t, os.Mkdir(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte("Changed"), 0644))
+
+		_, err := extractEntry(folder, entry("Hello World"), archiveMaxBytes, false)
+		assert.True(t, errors.Is(err, errFileExists), err)
+		content, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "Changed", string(content))
+
+		n, err := extractEntry(folder, entry("Hello World"), archiveMaxBytes, true)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(11), n)
+		content, err = ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello World", string(content))
+	})
+}
@@ -0,0 +1,537 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MIME_TYPE_TEXT_CSV = "text/csv"
+	MIME_TYPE_TEXT_TSV = "text/tab-separated-values"
+)
+
+const (
+	// csvFirstBatch is the number of rows read before SetSource returns, the remainder are streamed
+	csvFirstBatch = 100
+	// csvRefreshInterval is how often the table is refreshed while rows are streamed
+	csvRefreshInterval = 250 * time.Millisecond
+	// csvMeasureRows is the number of rows measured to size each column
+	csvMeasureRows    = 100
+	csvColumnMin      = 50
+	csvColumnMax      = 300
+	csvColumnFactor   = 1.25
+	csvSortAscending  = " ▲"
+	csvSortDescending = " ▼"
+)
+
+func init() {
+	RegisterViewer(MIME_TYPE_TEXT_CSV, "Table", PRIORITY_DEFAULT, func() (Viewer, error) {
+		return NewCSVViewer(','), nil
+	})
+	RegisterViewer(MIME_TYPE_TEXT_TSV, "Table", PRIORITY_DEFAULT, func() (Viewer, error) {
+		return NewCSVViewer('\t'), nil
+	})
+	RegisterExtension(".csv", MIME_TYPE_TEXT_CSV)
+	RegisterExtension(".tsv", MIME_TYPE_TEXT_TSV)
+}
+
+// CSVViewer shows comma or tab separated values in a table which can be sorted by column.
+type CSVViewer struct {
+	widget.BaseWidget
+	separator rune
+	header    []string
+	rows      [][]string
+	columns   int
+	// order holds the indices of rows in the order they are shown
+	order []int
+	// sortColumn is the column rows are sorted by, or -1 if unsorted
+	sortColumn int
+	descending bool
+	// hasHeader is true if the first record names the columns
+	hasHeader bool
+	widths    []float32
+	// column is the selected column which is resized
+	column  int
+	loading bool
+	err     error
+	// generation is incremented by each SetSource so rows from a previous source are discarded
+	generation int
+	lock       sync.Mutex
+}
+
+// NewCSVViewer returns a CSVViewer for values delimited by the given separator.
+func NewCSVViewer(separator rune) *CSVViewer {
+	v := &CSVViewer{
+		separator:  separator,
+		sortColumn: -1,
+		hasHeader:  true,
+	}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *CSVViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &csvViewerRenderer{
+		viewer: v,
+		status: widget.NewLabel(""),
+	}
+	r.table = &widget.Table{
+		Length: v.length,
+		CreateCell: func() fyne.CanvasObject {
+			return &widget.Label{
+				Wrapping: fyne.TextTruncate,
+			}
+		},
+		UpdateCell: v.updateCell,
+	}
+	r.table.OnSelected = func(id widget.TableCellID) {
+		v.lock.Lock()
+		v.column = id.Col
+		header := v.hasHeader && id.Row == 0
+		v.lock.Unlock()
+		if header {
+			v.SortBy(id.Col)
+			r.table.Unselect(id)
+		}
+	}
+	r.table.ExtendBaseWidget(r.table)
+	r.header = widget.NewCheck("Header", v.SetHeader)
+	r.header.Checked = true
+	toolbar := widget.NewToolbar(
+		widget.NewToolbarAction(theme.ContentRemoveIcon(), func() {
+			v.resizeColumn(1 / csvColumnFactor)
+		}),
+		widget.NewToolbarAction(theme.ContentAddIcon(), func() {
+			v.resizeColumn(csvColumnFactor)
+		}),
+		widget.NewToolbarAction(theme.ZoomFitIcon(), func() {
+			v.fitColumns()
+			v.Refresh()
+		}),
+	)
+	r.content = container.NewBorder(container.NewBorder(nil, nil, toolbar, r.header, r.status), nil, nil, nil, r.table)
+	r.objects = []fyne.CanvasObject{r.content}
+	r.Refresh()
+	return r
+}
+
+func (v *CSVViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+// SetSource reads the first rows before returning, and then streams the remainder so large files can be viewed while loading.
+func (v *CSVViewer) SetSource(source io.Reader) error {
+	reader := csv.NewReader(source)
+	reader.Comma = v.separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = false
+
+	var rows [][]string
+	for len(rows) < csvFirstBatch {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		rows = append(rows, record)
+	}
+	done := len(rows) < csvFirstBatch
+
+	v.lock.Lock()
+	v.generation++
+	generation := v.generation
+	v.header = nil
+	v.rows = nil
+	v.columns = 0
+	v.order = nil
+	v.widths = nil
+	v.err = nil
+	v.loading = !done
+	v.appendRows(rows, nil, -1, false)
+	v.fitColumnsLocked()
+	v.lock.Unlock()
+	v.Refresh()
+
+	if !done {
+		go v.stream(reader, generation)
+	}
+	return nil
+}
+
+// stream reads the remaining rows, refreshing the table periodically.
+func (v *CSVViewer) stream(reader *csv.Reader, generation int) {
+	var rows [][]string
+	last := time.Now()
+	flush := func(loading bool, err error) bool {
+		v.lock.Lock()
+		column, descending := v.sortColumn, v.descending
+		v.lock.Unlock()
+		// Sort the batch before acquiring the lock so the table stays responsive, it is then merged into the sorted rows
+		permutation := sortCSVBatch(rows, column, descending)
+		v.lock.Lock()
+		current := v.generation == generation
+		if current {
+			v.appendRows(rows, permutation, column, descending)
+			v.loading = loading
+			v.err = err
+		}
+		v.lock.Unlock()
+		rows = nil
+		last = time.Now()
+		if current {
+			v.Refresh()
+		}
+		return current
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			flush(false, nil)
+			return
+		} else if err != nil {
+			flush(false, err)
+			return
+		}
+		rows = append(rows, record)
+		if time.Since(last) > csvRefreshInterval && !flush(true, nil) {
+			// Source was replaced
+			return
+		}
+	}
+}
+
+// appendRows adds the rows, keeping them sorted. If the permutation is given it orders the rows by the given column, as returned by sortCSVBatch. The lock must be held.
+func (v *CSVViewer) appendRows(rows [][]string, permutation []int, column int, descending bool) {
+	start := len(v.rows)
+	for _, r := range rows {
+		if len(r) > v.columns {
+			v.columns = len(r)
+		}
+		v.rows = append(v.rows, r)
+	}
+	switch {
+	case v.sortColumn < 0:
+		// Rows are in file order, so the new rows follow the existing ones
+		for i := range rows {
+			v.order = append(v.order, start+i)
+		}
+	case len(v.order) == 0 || permutation == nil || column != v.sortColumn || descending != v.descending:
+		// Batch isn't sorted by the current column
+		for i := range rows {
+			v.order = append(v.order, start+i)
+		}
+		v.sortRows()
+	default:
+		v.mergeRows(start, permutation)
+	}
+}
+
+// mergeRows merges the rows starting at the given index, ordered by the permutation, into the sorted rows. The lock must be held.
+func (v *CSVViewer) mergeRows(start int, permutation []int) {
+	head := 0
+	if v.hasHeader && len(v.order) > 0 && v.order[0] == 0 {
+		// Keep the header first
+		head = 1
+	}
+	existing := v.order[head:]
+	merged := make([]int, head, len(v.order)+len(permutation))
+	copy(merged, v.order[:head])
+	i, j := 0, 0
+	for i < len(existing) && j < len(permutation) {
+		a, b := existing[i], start+permutation[j]
+		if csvLess(b, a, v.rows[b], v.rows[a], v.sortColumn, v.descending) {
+			merged = append(merged, b)
+			j++
+		} else {
+			merged = append(merged, a)
+			i++
+		}
+	}
+	merged = append(merged, existing[i:]...)
+	for ; j < len(permutation); j++ {
+		merged = append(merged, start+permutation[j])
+	}
+	v.order = merged
+}
+
+// SetHeader sets whether the first record names the columns, rather than holding values.
+func (v *CSVViewer) SetHeader(header bool) {
+	v.lock.Lock()
+	v.hasHeader = header
+	v.sortRows()
+	v.fitColumnsLocked()
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// SortBy sorts the rows by the given column, reversing the order if they are already sorted by it.
+func (v *CSVViewer) SortBy(column int) {
+	v.lock.Lock()
+	if v.sortColumn == column {
+		v.descending = !v.descending
+	} else {
+		v.sortColumn = column
+		v.descending = false
+	}
+	v.sortRows()
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// sortRows orders the rows, excluding any header, by the sort column. The lock must be held.
+func (v *CSVViewer) sortRows() {
+	if len(v.order) == 0 {
+		return
+	}
+	// Keep the header first
+	order := v.order
+	if v.hasHeader {
+		for i, o := range order {
+			if o == 0 {
+				order[0], order[i] = order[i], order[0]
+				break
+			}
+		}
+		order = order[1:]
+	}
+	column := v.sortColumn
+	if column < 0 {
+		sort.Ints(order)
+		return
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		return csvLess(a, b, v.rows[a], v.rows[b], column, v.descending)
+	})
+}
+
+// sortCSVBatch returns the indices of the rows ordered by the given column, or nil if the rows aren't sorted.
+func sortCSVBatch(rows [][]string, column int, descending bool) []int {
+	if column < 0 {
+		return nil
+	}
+	permutation := make([]int, len(rows))
+	for i := range permutation {
+		permutation[i] = i
+	}
+	sort.Slice(permutation, func(i, j int) bool {
+		a, b := permutation[i], permutation[j]
+		return csvLess(a, b, rows[a], rows[b], column, descending)
+	})
+	return permutation
+}
+
+// csvLess returns true if row x, at index a, comes before row y, at index b, when sorted by the given column. Equal values keep the order of the file.
+func csvLess(a, b int, x, y []string, column int, descending bool) bool {
+	c := compareCSVValues(csvValue(x, column), csvValue(y, column))
+	if c == 0 {
+		return a < b
+	}
+	if descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (v *CSVViewer) length() (int, int) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return len(v.order), v.columns
+}
+
+func (v *CSVViewer) updateCell(id widget.TableCellID, cell fyne.CanvasObject) {
+	v.lock.Lock()
+	if id.Row < 0 || id.Row >= len(v.order) {
+		v.lock.Unlock()
+		return
+	}
+	header := v.hasHeader && id.Row == 0
+	text := csvValue(v.rows[v.order[id.Row]], id.Col)
+	if header && id.Col == v.sortColumn {
+		if v.descending {
+			text += csvSortDescending
+		} else {
+			text += csvSortAscending
+		}
+	}
+	v.lock.Unlock()
+	label := cell.(*widget.Label)
+	label.TextStyle = fyne.TextStyle{
+		Bold: header,
+	}
+	label.SetText(text)
+}
+
+// resizeColumn scales the width of the selected column by the given factor.
+func (v *CSVViewer) resizeColumn(factor float32) {
+	v.lock.Lock()
+	if v.column < 0 || v.column >= len(v.widths) {
+		v.lock.Unlock()
+		return
+	}
+	v.widths[v.column] = float32(math.Max(csvColumnMin, float64(v.widths[v.column]*factor)))
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+func (v *CSVViewer) fitColumns() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.fitColumnsLocked()
+}
+
+// fitColumnsLocked sizes each column to fit the first rows. The lock must be held.
+func (v *CSVViewer) fitColumnsLocked() {
+	v.widths = make([]float32, v.columns)
+	for i, r := range v.rows {
+		if i >= csvMeasureRows {
+			break
+		}
+		style := fyne.TextStyle{
+			Bold: i == 0 && v.hasHeader,
+		}
+		for c, value := range r {
+			if w := fyne.MeasureText(value+csvSortAscending, theme.TextSize(), style).Width; w > v.widths[c] {
+				v.widths[c] = w
+			}
+		}
+	}
+	for c, w := range v.widths {
+		w += theme.Padding() * 4
+		v.widths[c] = float32(math.Max(csvColumnMin, math.Min(csvColumnMax, float64(w))))
+	}
+}
+
+type csvViewerRenderer struct {
+	viewer  *CSVViewer
+	table   *widget.Table
+	status  *widget.Label
+	header  *widget.Check
+	content *fyne.Container
+	objects []fyne.CanvasObject
+}
+
+func (r *csvViewerRenderer) Destroy() {}
+
+func (r *csvViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *csvViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *csvViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *csvViewerRenderer) Refresh() {
+	v := r.viewer
+	v.lock.Lock()
+	count := len(v.rows)
+	if v.hasHeader && count > 0 {
+		count--
+	}
+	status := fmt.Sprintf("%d rows", count)
+	if count == 1 {
+		status = "1 row"
+	}
+	if v.loading {
+		status += ", loading…"
+	}
+	if v.err != nil {
+		status += ", " + v.err.Error()
+	}
+	widths := append([]float32{}, v.widths...)
+	header := v.hasHeader
+	v.lock.Unlock()
+	r.status.SetText(status)
+	r.header.Checked = header
+	r.header.Refresh()
+	for c, w := range widths {
+		r.table.SetColumnWidth(c, w)
+	}
+	r.table.Refresh()
+}
+
+// csvValue returns the value in the given column, or an empty string if the row is short.
+func csvValue(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+	return row[column]
+}
+
+// compareCSVValues compares values numerically if both are numbers, otherwise alphabetically ignoring case.
+func compareCSVValues(a, b string) int {
+	if x, ok := parseCSVNumber(a); ok {
+		if y, ok := parseCSVNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// parseCSVNumber parses numbers as commonly formatted in financial exports, such as "$1,234.50" or "(12.00)".
+func parseCSVNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasPrefix(s, "-") {
+		negative = !negative
+		s = s[1:]
+	}
+	s = strings.TrimLeft(s, "$£€¥")
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		f = -f
+	}
+	return f, true
+}
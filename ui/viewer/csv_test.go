@@ -0,0 +1,163 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVNumber(t *testing.T) {
+	for name, tt := range map[string]struct {
+		given string
+		want  float64
+		ok    bool
+	}{
+		"Integer":  {"42", 42, true},
+		"Negative": {"-3.5", -3.5, true},
+		"Currency": {"$1,234.50", 1234.5, true},
+		"Brackets": {"(12.00)", -12, true},
+		"Spaces":   {" 7 ", 7, true},
+		"Empty":    {"", 0, false},
+		"Symbol":   {"$", 0, false},
+		"Text":     {"abc", 0, false},
+		"Mixed":    {"12abc", 0, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, ok := parseCSVNumber(tt.given)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompareCSVValues(t *testing.T) {
+	// Numbers compare numerically
+	assert.Equal(t, -1, compareCSVValues("9", "10"))
+	assert.Equal(t, 1, compareCSVValues("$1,000", "(5)"))
+	assert.Equal(t, 0, compareCSVValues("1.0", "1"))
+	// Text compares alphabetically ignoring case
+	assert.Equal(t, -1, compareCSVValues("apple", "Banana"))
+	assert.Equal(t, 0, compareCSVValues("ABC", "abc"))
+	// Numbers and text compare as text
+	assert.Equal(t, -1, compareCSVValues("10", "9a"))
+}
+
+// csvRows returns count rows, with a header, holding the index and a value which repeats so some rows are equal.
+func csvRows(start, count int) [][]string {
+	var rows [][]string
+	for i := start; i < start+count; i++ {
+		rows = append(rows, []string{fmt.Sprint(i), fmt.Sprint((i * 7) % 13)})
+	}
+	return rows
+}
+
+// sortedOrder returns the order of the viewer's rows as produced by sorting them all at once.
+func sortedOrder(v *CSVViewer) []int {
+	order := make([]int, len(v.rows))
+	for i := range order {
+		order[i] = i
+	}
+	w := &CSVViewer{
+		rows:       v.rows,
+		order:      order,
+		sortColumn: v.sortColumn,
+		descending: v.descending,
+		hasHeader:  v.hasHeader,
+	}
+	w.sortRows()
+	return w.order
+}
+
+func TestCSVViewer_AppendRows(t *testing.T) {
+	for name, tt := range map[string]struct {
+		column     int
+		descending bool
+		header     bool
+	}{
+		"Unsorted":           {-1, false, true},
+		"Ascending":          {1, false, true},
+		"Descending":         {1, true, true},
+		"Ascending_NoHeader": {1, false, false},
+		"Text":               {0, false, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v := &CSVViewer{
+				sortColumn: tt.column,
+				descending: tt.descending,
+				hasHeader:  tt.header,
+			}
+			v.appendRows(append([][]string{{"Index", "Value"}}, csvRows(1, 20)...), nil, -1, false)
+			for start := 21; start < 100; start += 20 {
+				batch := csvRows(start, 20)
+				v.appendRows(batch, sortCSVBatch(batch, tt.column, tt.descending), tt.column, tt.descending)
+			}
+			assert.Equal(t, 2, v.columns)
+			assert.Equal(t, 101, len(v.order))
+			assert.Equal(t, sortedOrder(v), v.order)
+			if tt.header {
+				assert.Equal(t, 0, v.order[0])
+			}
+		})
+	}
+}
+
+func TestCSVViewer_AppendRows_SortChanged(t *testing.T) {
+	v := &CSVViewer{
+		sortColumn: 1,
+		hasHeader:  true,
+	}
+	v.appendRows(csvRows(0, 20), nil, -1, false)
+	// Batch was sorted before the sort column changed
+	batch := csvRows(20, 20)
+	permutation := sortCSVBatch(batch, 0, false)
+	v.descending = true
+	v.appendRows(batch, permutation, 0, false)
+	assert.Equal(t, sortedOrder(v), v.order)
+}
+
+func TestCSVViewer_Stream(t *testing.T) {
+	test.NewApp()
+	v := NewCSVViewer(',')
+	v.SortBy(1)
+	var b strings.Builder
+	b.WriteString("Index,Value\n")
+	for _, r := range csvRows(1, 10*csvFirstBatch) {
+		b.WriteString(strings.Join(r, ",") + "\n")
+	}
+	assert.Nil(t, v.SetSource(strings.NewReader(b.String())))
+	assert.Eventually(t, func() bool {
+		v.lock.Lock()
+		defer v.lock.Unlock()
+		return !v.loading
+	}, time.Second, 10*time.Millisecond)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	assert.Nil(t, v.err)
+	assert.Equal(t, 10*csvFirstBatch+1, len(v.order))
+	assert.Equal(t, 0, v.order[0])
+	assert.True(t, sort.SliceIsSorted(v.order[1:], func(i, j int) bool {
+		a, b := v.order[1+i], v.order[1+j]
+		return csvLess(a, b, v.rows[a], v.rows[b], 1, false)
+	}))
+}
@@ -0,0 +1,315 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	EXIF_TAG_MAKE               = 0x010F
+	EXIF_TAG_MODEL              = 0x0110
+	EXIF_TAG_ORIENTATION        = 0x0112
+	EXIF_TAG_SOFTWARE           = 0x0131
+	EXIF_TAG_DATE_TIME          = 0x0132
+	EXIF_TAG_EXPOSURE_TIME      = 0x829A
+	EXIF_TAG_F_NUMBER           = 0x829D
+	EXIF_TAG_EXIF_IFD           = 0x8769
+	EXIF_TAG_GPS_IFD            = 0x8825
+	EXIF_TAG_ISO                = 0x8827
+	EXIF_TAG_DATE_TIME_ORIGINAL = 0x9003
+	EXIF_TAG_FOCAL_LENGTH       = 0x920A
+	EXIF_TAG_LENS_MODEL         = 0xA434
+
+	EXIF_GPS_LATITUDE_REF  = 0x0001
+	EXIF_GPS_LATITUDE      = 0x0002
+	EXIF_GPS_LONGITUDE_REF = 0x0003
+	EXIF_GPS_LONGITUDE     = 0x0004
+	EXIF_GPS_ALTITUDE_REF  = 0x0005
+	EXIF_GPS_ALTITUDE      = 0x0006
+)
+
+var errNoExif = errors.New("No EXIF metadata")
+
+// exifData holds the entries of an image's primary, EXIF, and GPS directories.
+type exifData struct {
+	ifd0 map[uint16]*exifEntry
+	exif map[uint16]*exifEntry
+	gps  map[uint16]*exifEntry
+}
+
+// exifEntry is a tag read from an image's EXIF metadata.
+type exifEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	// value holds the raw bytes in the given byte order
+	value []byte
+	order binary.ByteOrder
+}
+
+// integer returns the value at the given index of an integer entry.
+func (e *exifEntry) integer(index int) (uint32, bool) {
+	switch e.kind {
+	case 1, 7: // BYTE, UNDEFINED
+		if index < len(e.value) {
+			return uint32(e.value[index]), true
+		}
+	case 3: // SHORT
+		if o := index * 2; o+2 <= len(e.value) {
+			return uint32(e.order.Uint16(e.value[o:])), true
+		}
+	case 4, 9: // LONG, SLONG
+		if o := index * 4; o+4 <= len(e.value) {
+			return e.order.Uint32(e.value[o:]), true
+		}
+	}
+	return 0, false
+}
+
+// rational returns the value at the given index of a rational entry.
+func (e *exifEntry) rational(index int) (float64, bool) {
+	o := index * 8
+	if o+8 > len(e.value) {
+		return 0, false
+	}
+	switch e.kind {
+	case 5: // RATIONAL
+		n, d := e.order.Uint32(e.value[o:]), e.order.Uint32(e.value[o+4:])
+		if d == 0 {
+			return 0, false
+		}
+		return float64(n) / float64(d), true
+	case 10: // SRATIONAL
+		n, d := int32(e.order.Uint32(e.value[o:])), int32(e.order.Uint32(e.value[o+4:]))
+		if d == 0 {
+			return 0, false
+		}
+		return float64(n) / float64(d), true
+	}
+	return 0, false
+}
+
+// String returns the value of the entry formatted for display.
+func (e *exifEntry) String() string {
+	switch e.kind {
+	case 2: // ASCII
+		return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+	case 5, 10: // RATIONAL, SRATIONAL
+		var values []string
+		for i := 0; i < int(e.count); i++ {
+			if v, ok := e.rational(i); ok {
+				values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		return strings.Join(values, ", ")
+	case 7: // UNDEFINED
+		return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+	}
+	var values []string
+	for i := 0; i < int(e.count); i++ {
+		if v, ok := e.integer(i); ok {
+			if e.kind == 9 {
+				values = append(values, strconv.FormatInt(int64(int32(v)), 10))
+			} else {
+				values = append(values, strconv.FormatUint(uint64(v), 10))
+			}
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// exifTypeSize returns the size in bytes of a single value of the given type.
+func exifTypeSize(t uint16) int {
+	switch t {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
+
+// exifTIFF returns the TIFF structure holding the EXIF metadata of a JPEG or TIFF image.
+func exifTIFF(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return data, nil
+	}
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil, errNoExif
+	}
+	// Walk the JPEG segments looking for APP1
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil, errNoExif
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		i += 2 + length
+	}
+	return nil, errNoExif
+}
+
+// readExifIFD returns the entries of the image file directory at the given offset, and the offset of the next directory.
+func readExifIFD(tiff []byte, order binary.ByteOrder, offset uint32) (map[uint16]*exifEntry, uint32, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, 0, errors.New("Invalid EXIF directory offset")
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	start := int(offset) + 2
+	if start+count*12+4 > len(tiff) {
+		return nil, 0, errors.New("Invalid EXIF directory size")
+	}
+	entries := make(map[uint16]*exifEntry, count)
+	for i := 0; i < count; i++ {
+		e := tiff[start+i*12:]
+		entry := &exifEntry{
+			tag:   order.Uint16(e),
+			kind:  order.Uint16(e[2:]),
+			count: order.Uint32(e[4:]),
+			order: order,
+		}
+		size := uint64(exifTypeSize(entry.kind)) * uint64(entry.count)
+		if size == 0 {
+			continue
+		}
+		if size <= 4 {
+			entry.value = e[8 : 8+size]
+		} else {
+			o := uint64(order.Uint32(e[8:]))
+			if o+size > uint64(len(tiff)) {
+				// Skip values that fall outside the data
+				continue
+			}
+			entry.value = tiff[o : o+size]
+		}
+		entries[entry.tag] = entry
+	}
+	return entries, order.Uint32(tiff[start+count*12:]), nil
+}
+
+// readExif returns the entries of the primary image file directory, and of the EXIF and GPS directories it references.
+func readExif(data []byte) (*exifData, error) {
+	tiff, err := exifTIFF(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiff) < 8 {
+		return nil, errNoExif
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errNoExif
+	}
+	ifd0, _, err := readExifIFD(tiff, order, order.Uint32(tiff[4:]))
+	if err != nil {
+		return nil, err
+	}
+	x := &exifData{
+		ifd0: ifd0,
+	}
+	// Sub-directories are optional so errors reading them are ignored
+	if e, ok := ifd0[EXIF_TAG_EXIF_IFD]; ok {
+		if o, ok := e.integer(0); ok {
+			x.exif, _, _ = readExifIFD(tiff, order, o)
+		}
+	}
+	if e, ok := ifd0[EXIF_TAG_GPS_IFD]; ok {
+		if o, ok := e.integer(0); ok {
+			x.gps, _, _ = readExifIFD(tiff, order, o)
+		}
+	}
+	return x, nil
+}
+
+// orientation returns the orientation recorded in the metadata, or 1 if there is none.
+func (x *exifData) orientation() int {
+	if e, ok := x.ifd0[EXIF_TAG_ORIENTATION]; ok {
+		if o, ok := e.integer(0); ok && o >= 1 && o <= 8 {
+			return int(o)
+		}
+	}
+	return 1
+}
+
+// coordinate returns the GPS latitude or longitude in decimal degrees, negative for south or west.
+func (x *exifData) coordinate(tag, ref uint16) (float64, bool) {
+	e, ok := x.gps[tag]
+	if !ok {
+		return 0, false
+	}
+	var degrees float64
+	for i, divisor := range []float64{1, 60, 3600} {
+		v, ok := e.rational(i)
+		if !ok {
+			return 0, false
+		}
+		degrees += v / divisor
+	}
+	if r, ok := x.gps[ref]; ok {
+		if s := r.String(); s == "S" || s == "W" {
+			degrees = -degrees
+		}
+	}
+	return degrees, true
+}
+
+// altitude returns the GPS altitude in meters, negative if below sea level.
+func (x *exifData) altitude() (float64, bool) {
+	e, ok := x.gps[EXIF_GPS_ALTITUDE]
+	if !ok {
+		return 0, false
+	}
+	a, ok := e.rational(0)
+	if !ok {
+		return 0, false
+	}
+	if r, ok := x.gps[EXIF_GPS_ALTITUDE_REF]; ok {
+		if b, ok := r.integer(0); ok && b == 1 {
+			a = -a
+		}
+	}
+	return a, true
+}
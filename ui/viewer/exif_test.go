@@ -0,0 +1,157 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testExifEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+func rationals(values ...uint32) []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, values)
+	return buffer.Bytes()
+}
+
+// buildTIFF returns a little endian TIFF structure holding the given primary and GPS directories.
+func buildTIFF(ifd0, gps []testExifEntry) []byte {
+	size := func(entries []testExifEntry) uint32 {
+		return uint32(2 + 12*len(entries) + 4)
+	}
+	if len(gps) > 0 {
+		ifd0 = append(ifd0, testExifEntry{EXIF_TAG_GPS_IFD, 4, 1, nil})
+	}
+	gpsOffset := 8 + size(ifd0)
+	dataOffset := gpsOffset
+	if len(gps) > 0 {
+		dataOffset += size(gps)
+		ifd0[len(ifd0)-1].value = rationals(gpsOffset)
+	}
+	var ifds, data bytes.Buffer
+	write := func(entries []testExifEntry) {
+		binary.Write(&ifds, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&ifds, binary.LittleEndian, e.tag)
+			binary.Write(&ifds, binary.LittleEndian, e.kind)
+			binary.Write(&ifds, binary.LittleEndian, e.count)
+			if len(e.value) <= 4 {
+				value := make([]byte, 4)
+				copy(value, e.value)
+				ifds.Write(value)
+			} else {
+				binary.Write(&ifds, binary.LittleEndian, dataOffset+uint32(data.Len()))
+				data.Write(e.value)
+			}
+		}
+		// No next directory
+		binary.Write(&ifds, binary.LittleEndian, uint32(0))
+	}
+	write(ifd0)
+	if len(gps) > 0 {
+		write(gps)
+	}
+	tiff := []byte("II*\x00")
+	tiff = append(tiff, rationals(8)...)
+	tiff = append(tiff, ifds.Bytes()...)
+	return append(tiff, data.Bytes()...)
+}
+
+// wrapJPEG returns a minimal JPEG holding the TIFF structure in an APP1 segment.
+func wrapJPEG(tiff []byte) []byte {
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00, 0xFF, 0xE1}
+	jpeg = append(jpeg, byte((len(segment)+2)>>8), byte(len(segment)+2))
+	jpeg = append(jpeg, segment...)
+	return append(jpeg, 0xFF, 0xD9)
+}
+
+func testTIFF() []byte {
+	return buildTIFF([]testExifEntry{
+		{EXIF_TAG_MAKE, 2, 6, []byte("Canon\x00")},
+		{EXIF_TAG_ORIENTATION, 3, 1, []byte{6, 0}},
+	}, []testExifEntry{
+		{EXIF_GPS_LATITUDE_REF, 2, 2, []byte("S\x00")},
+		{EXIF_GPS_LATITUDE, 5, 3, rationals(33, 1, 51, 1, 36, 1)},
+		{EXIF_GPS_LONGITUDE_REF, 2, 2, []byte("E\x00")},
+		{EXIF_GPS_LONGITUDE, 5, 3, rationals(151, 1, 12, 1, 54, 1)},
+		{EXIF_GPS_ALTITUDE_REF, 1, 1, []byte{1}},
+		{EXIF_GPS_ALTITUDE, 5, 1, rationals(25, 2)},
+	})
+}
+
+func TestReadExif(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"TIFF": testTIFF(),
+		"JPEG": wrapJPEG(testTIFF()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			x, err := readExif(data)
+			assert.Nil(t, err)
+			assert.Equal(t, "Canon", x.ifd0[EXIF_TAG_MAKE].String())
+			assert.Equal(t, 6, x.orientation())
+			latitude, ok := x.coordinate(EXIF_GPS_LATITUDE, EXIF_GPS_LATITUDE_REF)
+			assert.True(t, ok)
+			assert.InDelta(t, -33.86, latitude, 0.001)
+			longitude, ok := x.coordinate(EXIF_GPS_LONGITUDE, EXIF_GPS_LONGITUDE_REF)
+			assert.True(t, ok)
+			assert.InDelta(t, 151.215, longitude, 0.001)
+			altitude, ok := x.altitude()
+			assert.True(t, ok)
+			assert.Equal(t, -12.5, altitude)
+		})
+	}
+}
+
+func TestReadExif_Invalid(t *testing.T) {
+	t.Run("NoExif", func(t *testing.T) {
+		_, err := readExif([]byte("\x89PNG\r\n\x1a\n"))
+		assert.Equal(t, errNoExif, err)
+	})
+	t.Run("NoOrientation", func(t *testing.T) {
+		x, err := readExif(buildTIFF([]testExifEntry{
+			{EXIF_TAG_MAKE, 2, 6, []byte("Canon\x00")},
+		}, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, x.orientation())
+		_, ok := x.altitude()
+		assert.False(t, ok)
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		data := testTIFF()
+		for i := range data {
+			// Must not panic however the data is cut short
+			readExif(data[:i])
+			readExif(wrapJPEG(data[:i]))
+		}
+	})
+	t.Run("OutOfRange", func(t *testing.T) {
+		data := testTIFF()
+		// Point the primary directory beyond the end of the data
+		binary.LittleEndian.PutUint32(data[4:], uint32(len(data)+100))
+		_, err := readExif(data)
+		assert.NotNil(t, err)
+	})
+}
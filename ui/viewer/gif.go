@@ -0,0 +1,233 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"time"
+)
+
+// GIF delays shorter than this are commonly treated as the default by browsers
+const (
+	gifMinimumDelay = 20 * time.Millisecond
+	gifDefaultDelay = 100 * time.Millisecond
+)
+
+// gifMaxBytes limits the memory used by the composited frames of an animation, each of which takes 4 bytes per pixel
+const gifMaxBytes = 128 * 1024 * 1024
+
+// errAnimationTooLarge is returned when the frames of an animation would exceed the memory budget, so only the first frame is shown.
+var errAnimationTooLarge = errors.New("Animation is too large to play")
+
+// imageAnimation holds the composited frames of an animated image and the state of its playback.
+type imageAnimation struct {
+	frames []image.Image
+	delays []time.Duration
+	// loopCount is the number of times to repeat, 0 to repeat forever, or -1 to play once
+	loopCount int
+	frame     int
+	loops     int
+	// stop is closed to end playback, and is nil when paused
+	stop chan struct{}
+}
+
+// decodeGIF decodes a GIF, returning its first frame, and its animation if it has more than one frame.
+// If the frames would exceed the memory budget, the first frame is returned with errAnimationTooLarge.
+func decodeGIF(data []byte) (image.Image, *imageAnimation, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(config.Width)*int64(config.Height)*4 > gifMaxBytes {
+		// Even a single composited frame exceeds the budget, so only decode the first frame
+		first, err := gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return first, nil, errAnimationTooLarge
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	first := g.Image[0]
+	if len(g.Image) < 2 {
+		return first, nil, nil
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = first.Bounds()
+	}
+	if int64(bounds.Dx())*int64(bounds.Dy())*4*int64(len(g.Image)) > gifMaxBytes {
+		return first, nil, errAnimationTooLarge
+	}
+	a := &imageAnimation{
+		loopCount: g.LoopCount,
+	}
+	// Composite each frame onto the previous according to its disposal method
+	current := image.NewNRGBA(bounds)
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(current)
+		}
+		draw.Draw(current, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		a.frames = append(a.frames, cloneNRGBA(current))
+		delay := gifDefaultDelay
+		if i < len(g.Delay) {
+			if d := time.Duration(g.Delay[i]) * 10 * time.Millisecond; d >= gifMinimumDelay {
+				delay = d
+			}
+		}
+		a.delays = append(a.delays, delay)
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(current, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			current = previous
+		}
+	}
+	return first, a, nil
+}
+
+// details describes the frames and timing of the animation.
+func (a *imageAnimation) details() []*imageDetail {
+	var duration time.Duration
+	for _, d := range a.delays {
+		duration += d
+	}
+	loops := "Forever"
+	switch {
+	case a.loopCount < 0:
+		loops = "Once"
+	case a.loopCount > 0:
+		loops = fmt.Sprintf("%d times", a.loopCount+1)
+	}
+	return []*imageDetail{
+		{"Frames", fmt.Sprintf("%d", len(a.frames))},
+		{"Duration", duration.String()},
+		{"Plays", loops},
+	}
+}
+
+func cloneNRGBA(img *image.NRGBA) *image.NRGBA {
+	c := image.NewNRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
+
+// Play starts the animation, restarting it if it had finished.
+func (v *ImageViewer) Play() {
+	v.lock.Lock()
+	a := v.animation
+	if a == nil || a.stop != nil {
+		v.lock.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	a.stop = stop
+	if a.loopCount != 0 && a.loops > a.loopCount {
+		// Finished, so start again
+		a.loops = 0
+		a.frame = 0
+	}
+	v.lock.Unlock()
+	v.Refresh()
+	go v.animate(a, stop)
+}
+
+// Pause stops the animation at the current frame.
+func (v *ImageViewer) Pause() {
+	v.lock.Lock()
+	a := v.animation
+	if a == nil || a.stop == nil {
+		v.lock.Unlock()
+		return
+	}
+	close(a.stop)
+	a.stop = nil
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// TogglePlayback pauses a playing animation, or plays a paused one.
+func (v *ImageViewer) TogglePlayback() {
+	v.lock.Lock()
+	playing := v.animation != nil && v.animation.stop != nil
+	v.lock.Unlock()
+	if playing {
+		v.Pause()
+	} else {
+		v.Play()
+	}
+}
+
+// Stop ends playback of any animation.
+func (v *ImageViewer) Stop() {
+	v.Pause()
+}
+
+// animate shows each frame of the animation for its delay until stopped or the loop count is reached.
+func (v *ImageViewer) animate(a *imageAnimation, stop chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		v.lock.Lock()
+		delay := a.delays[a.frame]
+		v.lock.Unlock()
+		timer.Reset(delay)
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		v.lock.Lock()
+		if a.stop != stop {
+			// Paused while waiting
+			v.lock.Unlock()
+			return
+		}
+		next := a.frame + 1
+		if next >= len(a.frames) {
+			a.loops++
+			if a.loopCount < 0 || (a.loopCount > 0 && a.loops > a.loopCount) {
+				// Finished, remain on the last frame
+				close(a.stop)
+				a.stop = nil
+				v.lock.Unlock()
+				v.Refresh()
+				return
+			}
+			next = 0
+		}
+		a.frame = next
+		v.source = a.frames[next]
+		v.img = rotateImage(v.source, v.rotation)
+		v.lock.Unlock()
+		v.Refresh()
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+// encodeGIF returns a GIF with the given number of single pixel frames on a canvas of the given size.
+func encodeGIF(t *testing.T, width, height, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{
+		Config: image.Config{
+			ColorModel: palette,
+			Width:      width,
+			Height:     height,
+		},
+	}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(i, 0, i+1, 1), palette)
+		frame.SetColorIndex(i, 0, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5)
+	}
+	var buffer bytes.Buffer
+	assert.Nil(t, gif.EncodeAll(&buffer, g))
+	return buffer.Bytes()
+}
+
+func TestDecodeGIF(t *testing.T) {
+	t.Run("Still", func(t *testing.T) {
+		first, a, err := decodeGIF(encodeGIF(t, 4, 4, 1))
+		assert.Nil(t, err)
+		assert.NotNil(t, first)
+		assert.Nil(t, a)
+	})
+	t.Run("Animated", func(t *testing.T) {
+		first, a, err := decodeGIF(encodeGIF(t, 4, 4, 3))
+		assert.Nil(t, err)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), first.Bounds())
+		assert.Equal(t, 3, len(a.frames))
+		assert.Equal(t, []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}, a.delays)
+		// Frames are composited onto the previous
+		_, _, _, alpha := a.frames[2].At(0, 0).RGBA()
+		assert.NotZero(t, alpha)
+	})
+	t.Run("TooLarge", func(t *testing.T) {
+		first, a, err := decodeGIF(encodeGIF(t, 4096, 4096, 3))
+		assert.Equal(t, errAnimationTooLarge, err)
+		assert.NotNil(t, first)
+		assert.Nil(t, a)
+	})
+	t.Run("TooLargeFrame", func(t *testing.T) {
+		// Frames after the first are corrupt, so decoding every frame would fail rather than return the first
+		data := encodeGIF(t, 8192, 8192, 3)
+		data = append(data[:len(data)-1], 0x21, 0xf9)
+		_, err := gif.DecodeAll(bytes.NewReader(data))
+		assert.NotNil(t, err)
+		first, a, err := decodeGIF(data)
+		assert.Equal(t, errAnimationTooLarge, err)
+		assert.NotNil(t, first)
+		assert.Nil(t, a)
+	})
+}
+
+func TestImageViewer_SetSource_AnimationTooLarge(t *testing.T) {
+	test.NewApp()
+	v := NewImageViewer()
+	assert.Nil(t, v.SetSource(bytes.NewReader(encodeGIF(t, 4096, 4096, 3))))
+	assert.Nil(t, v.animation)
+	// First frame is shown
+	assert.NotNil(t, v.source)
+	assert.Contains(t, v.details, &imageDetail{"Animation", errAnimationTooLarge.Error()})
+}
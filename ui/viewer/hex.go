@@ -0,0 +1,587 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const MIME_TYPE_APPLICATION_OCTET_STREAM = "application/octet-stream"
+
+const (
+	hexRowLength = 16
+	// hexPageSize is the number of bytes read from a sequential source at a time
+	hexPageSize = 64 * 1024
+	// hexMaxPages is the number of pages kept in memory when a sequential source can be reopened to read discarded pages again
+	hexMaxPages = 64
+	hexModeHex  = "Hex"
+	hexModeText = "Text"
+)
+
+func init() {
+	// Any file can be shown as bytes, but other viewers are preferred
+	RegisterViewer("*/*", "Hex", PRIORITY_FALLBACK, func() (Viewer, error) {
+		return NewHexViewer(), nil
+	})
+}
+
+// HexViewer shows the bytes of any file as hexadecimal and ASCII, reading large files a page at a time as they are scrolled.
+type HexViewer struct {
+	widget.BaseWidget
+	// source is read from when random access is not supported
+	source io.Reader
+	// reopener reads a sequential source again from the start, or is nil if it can't
+	reopener Reopener
+	// position is the offset of the next byte read from a sequential source
+	position int64
+	// random is read from when random access is supported, with size bytes
+	random io.ReaderAt
+	size   int64
+	// pages holds the bytes read from a sequential source keyed by page number, only those near the view are kept if the source can be reopened
+	pages map[int64][]byte
+	// length is the number of bytes read so far from a sequential source
+	length  int64
+	eof     bool
+	loading bool
+	err     error
+	// match is the offset of the last search match, or -1
+	match  int64
+	status string
+	list   *widget.List
+	lock   sync.Mutex
+	// read serializes reads from the source
+	read sync.Mutex
+}
+
+func NewHexViewer() *HexViewer {
+	v := &HexViewer{
+		match: -1,
+	}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *HexViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &hexViewerRenderer{
+		viewer: v,
+		status: widget.NewLabel(""),
+	}
+	r.list = &widget.List{
+		Length: func() int {
+			size, _ := v.available()
+			return int((size + hexRowLength - 1) / hexRowLength)
+		},
+		CreateItem: func() fyne.CanvasObject {
+			style := fyne.TextStyle{
+				Monospace: true,
+			}
+			offset := canvas.NewText(fmt.Sprintf("%08x", 0), theme.DisabledTextColor())
+			offset.TextStyle = style
+			values := canvas.NewText(strings.Repeat("00 ", hexRowLength), theme.TextColor())
+			values.TextStyle = style
+			ascii := canvas.NewText(strings.Repeat(".", hexRowLength), theme.PrimaryColor())
+			ascii.TextStyle = style
+			return container.NewHBox(offset, values, ascii)
+		},
+		UpdateItem: v.updateItem,
+	}
+	r.list.ExtendBaseWidget(r.list)
+	v.lock.Lock()
+	v.list = r.list
+	v.lock.Unlock()
+	r.offset = widget.NewEntry()
+	r.offset.SetPlaceHolder("Offset")
+	r.offset.OnSubmitted = func(s string) {
+		offset, err := parseHexOffset(s)
+		if err != nil {
+			v.setStatus(err.Error())
+			return
+		}
+		go v.Jump(offset)
+	}
+	r.mode = widget.NewSelect([]string{hexModeHex, hexModeText}, nil)
+	r.mode.SetSelected(hexModeHex)
+	r.search = widget.NewEntry()
+	r.search.SetPlaceHolder("Search")
+	search := func() {
+		pattern, err := parseHexPattern(r.search.Text, r.mode.Selected)
+		if err != nil {
+			v.setStatus(err.Error())
+			return
+		}
+		go v.Search(pattern)
+	}
+	// Restart the search from the beginning when the pattern changes
+	r.search.OnChanged = func(string) {
+		v.lock.Lock()
+		v.match = -1
+		v.lock.Unlock()
+	}
+	r.mode.OnChanged = r.search.OnChanged
+	r.search.OnSubmitted = func(string) {
+		search()
+	}
+	r.content = container.NewBorder(
+		container.NewBorder(nil, nil, container.NewGridWrap(fyne.NewSize(150, r.offset.MinSize().Height), r.offset), container.NewHBox(r.mode, widget.NewButtonWithIcon("", theme.SearchIcon(), search)), r.search),
+		r.status,
+		nil,
+		nil,
+		r.list,
+	)
+	r.objects = []fyne.CanvasObject{r.content}
+	r.Refresh()
+	return r
+}
+
+func (v *HexViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+// SetSource uses random access if the source supports it, otherwise bytes are read as they are needed.
+func (v *HexViewer) SetSource(source io.Reader) error {
+	var random io.ReaderAt
+	var size int64
+	if r, ok := source.(io.ReaderAt); ok {
+		if s, ok := source.(io.Seeker); ok {
+			if end, err := s.Seek(0, io.SeekEnd); err == nil {
+				random = r
+				size = end
+			}
+		}
+	}
+	reopener, _ := source.(Reopener)
+	v.read.Lock()
+	v.lock.Lock()
+	v.source = source
+	v.reopener = reopener
+	v.position = 0
+	v.random = random
+	v.size = size
+	v.pages = make(map[int64][]byte)
+	v.length = 0
+	v.eof = random != nil
+	v.err = nil
+	v.match = -1
+	v.status = ""
+	v.lock.Unlock()
+	v.read.Unlock()
+	if random == nil {
+		// Read the first page so the size is known for small files
+		if err := v.load(hexPageSize); err != nil {
+			return err
+		}
+	}
+	v.Refresh()
+	return nil
+}
+
+// Jump scrolls to show the byte at the given offset.
+func (v *HexViewer) Jump(offset int64) {
+	if err := v.load(offset + 1); err != nil {
+		v.setStatus(err.Error())
+		return
+	}
+	if size, _ := v.available(); offset >= size {
+		v.setStatus(fmt.Sprintf("Offset %#x is beyond the end of the file (%d bytes)", offset, size))
+		return
+	}
+	v.setStatus(fmt.Sprintf("Offset %#x", offset))
+	v.selectRow(int(offset / hexRowLength))
+}
+
+// Search finds the next occurrence of the pattern after the last match, and scrolls to show it.
+func (v *HexViewer) Search(pattern []byte) {
+	if len(pattern) == 0 {
+		return
+	}
+	v.lock.Lock()
+	start := v.match + 1
+	v.lock.Unlock()
+	v.setStatus("Searching…")
+	offset, err := v.find(pattern, start)
+	if err == nil && offset < 0 && start > 0 {
+		// Wrap around to the beginning
+		offset, err = v.find(pattern, 0)
+	}
+	if err != nil {
+		v.setStatus(err.Error())
+		return
+	}
+	v.lock.Lock()
+	v.match = offset
+	v.lock.Unlock()
+	if offset < 0 {
+		v.setStatus("Not found")
+		return
+	}
+	v.setStatus(fmt.Sprintf("Found at offset %#x", offset))
+	v.selectRow(int(offset / hexRowLength))
+}
+
+// find returns the offset of the first occurrence of the pattern at or after start, or -1 if there is none.
+func (v *HexViewer) find(pattern []byte, start int64) (int64, error) {
+	chunk := int64(hexPageSize)
+	overlap := int64(len(pattern) - 1)
+	for offset := start; ; offset += chunk {
+		data, err := v.bytes(offset, chunk+overlap)
+		if err != nil {
+			return -1, err
+		}
+		if i := bytes.Index(data, pattern); i >= 0 {
+			return offset + int64(i), nil
+		}
+		if int64(len(data)) < chunk+overlap {
+			return -1, nil
+		}
+	}
+}
+
+// available returns the number of bytes that can be shown, and whether the end of the file has been reached.
+func (v *HexViewer) available() (int64, bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.random != nil {
+		return v.size, true
+	}
+	return v.length, v.eof
+}
+
+// load reads from a sequential source until at least size bytes are available, or the end is reached.
+func (v *HexViewer) load(size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	_, err := v.page((size - 1) / hexPageSize)
+	return err
+}
+
+// page returns the bytes of the given page of a sequential source, reading it if necessary.
+func (v *HexViewer) page(number int64) ([]byte, error) {
+	v.read.Lock()
+	defer v.read.Unlock()
+	for {
+		v.lock.Lock()
+		data, cached := v.pages[number]
+		source := v.source
+		position := v.position
+		done := v.random != nil || (v.eof && number*hexPageSize >= v.length)
+		err := v.err
+		v.lock.Unlock()
+		if cached {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if done || source == nil {
+			return nil, nil
+		}
+		if number*hexPageSize < position {
+			// Page was discarded, so read the source again from the start
+			if err := v.reopen(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		page := make([]byte, hexPageSize)
+		n, err := io.ReadFull(source, page)
+		v.lock.Lock()
+		if n > 0 {
+			v.pages[position/hexPageSize] = page[:n]
+			v.discard(number)
+		}
+		v.position += int64(n)
+		grown := v.position > v.length
+		if grown {
+			v.length = v.position
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			v.eof = true
+		} else if err != nil {
+			v.err = err
+		}
+		v.lock.Unlock()
+		if grown {
+			v.Refresh()
+		}
+	}
+}
+
+// reopen replaces a sequential source with one reading again from the start, the caller must hold the read lock.
+func (v *HexViewer) reopen() error {
+	v.lock.Lock()
+	reopener := v.reopener
+	v.lock.Unlock()
+	if reopener == nil {
+		return errors.New("Source cannot be read again")
+	}
+	source, err := reopener.Reopen()
+	if err != nil {
+		return err
+	}
+	v.lock.Lock()
+	if c, ok := v.source.(io.Closer); ok {
+		c.Close()
+	}
+	v.source = source
+	v.position = 0
+	v.lock.Unlock()
+	return nil
+}
+
+// discard removes the pages furthest from the given page while there are too many, the caller must hold the lock.
+// Pages are only discarded if the source can be reopened to read them again.
+func (v *HexViewer) discard(number int64) {
+	if v.reopener == nil {
+		return
+	}
+	for len(v.pages) > hexMaxPages {
+		furthest, distance := int64(-1), int64(-1)
+		for p := range v.pages {
+			d := p - number
+			if d < 0 {
+				d = -d
+			}
+			if d > distance {
+				furthest, distance = p, d
+			}
+		}
+		delete(v.pages, furthest)
+	}
+}
+
+// bytes returns up to length bytes from the given offset, reading them from the source if necessary.
+func (v *HexViewer) bytes(offset, length int64) ([]byte, error) {
+	v.lock.Lock()
+	random := v.random
+	size := v.size
+	v.lock.Unlock()
+	if random != nil {
+		if offset >= size {
+			return nil, nil
+		}
+		if offset+length > size {
+			length = size - offset
+		}
+		data := make([]byte, length)
+		n, err := random.ReadAt(data, offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	var data []byte
+	for end := offset + length; offset < end; {
+		page, err := v.page(offset / hexPageSize)
+		if err != nil {
+			return nil, err
+		}
+		start := offset % hexPageSize
+		if start >= int64(len(page)) {
+			// Reached the end
+			break
+		}
+		n := int64(len(page)) - start
+		if n > end-offset {
+			n = end - offset
+		}
+		data = append(data, page[start:start+n]...)
+		offset += n
+	}
+	return data, nil
+}
+
+func (v *HexViewer) updateItem(id widget.ListItemID, item fyne.CanvasObject) {
+	offset := int64(id) * hexRowLength
+	data, err := v.rowBytes(offset)
+	if err != nil {
+		fyne.LogError("Failed to read bytes", err)
+	}
+	values := strings.Builder{}
+	ascii := strings.Builder{}
+	for i := 0; i < hexRowLength; i++ {
+		if i < len(data) {
+			fmt.Fprintf(&values, "%02x ", data[i])
+			if b := data[i]; b >= 0x20 && b < 0x7f {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		} else {
+			values.WriteString("   ")
+		}
+		if i == hexRowLength/2-1 {
+			values.WriteByte(' ')
+		}
+	}
+	objects := item.(*fyne.Container).Objects
+	objects[0].(*canvas.Text).Text = fmt.Sprintf("%08x", offset)
+	objects[1].(*canvas.Text).Text = values.String()
+	objects[2].(*canvas.Text).Text = ascii.String()
+	for _, o := range objects {
+		o.Refresh()
+	}
+
+	// Read the page of a sequential source if it was discarded, or the next page as the end is approached
+	number := offset / hexPageSize
+	v.lock.Lock()
+	_, cached := v.pages[number]
+	fetch := v.random == nil && !v.loading && v.err == nil && (!cached || (!v.eof && offset+hexPageSize/2 > v.length))
+	if fetch {
+		v.loading = true
+		if cached {
+			number = v.length / hexPageSize
+		}
+	}
+	v.lock.Unlock()
+	if fetch {
+		go func() {
+			if _, err := v.page(number); err != nil {
+				v.setStatus(err.Error())
+			}
+			v.lock.Lock()
+			v.loading = false
+			v.lock.Unlock()
+			v.Refresh()
+		}()
+	}
+}
+
+// rowBytes returns the bytes of the row at the given offset without blocking on a sequential source.
+func (v *HexViewer) rowBytes(offset int64) ([]byte, error) {
+	v.lock.Lock()
+	random := v.random
+	if random == nil {
+		defer v.lock.Unlock()
+		// Rows don't span pages as the page size is a multiple of the row length
+		page := v.pages[offset/hexPageSize]
+		start := offset % hexPageSize
+		if start >= int64(len(page)) {
+			return nil, nil
+		}
+		end := start + hexRowLength
+		if end > int64(len(page)) {
+			end = int64(len(page))
+		}
+		return page[start:end], nil
+	}
+	v.lock.Unlock()
+	return v.bytes(offset, hexRowLength)
+}
+
+func (v *HexViewer) selectRow(row int) {
+	v.lock.Lock()
+	list := v.list
+	v.lock.Unlock()
+	if list != nil {
+		list.Unselect(row)
+		list.Select(row)
+	}
+}
+
+func (v *HexViewer) setStatus(status string) {
+	v.lock.Lock()
+	v.status = status
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+type hexViewerRenderer struct {
+	viewer  *HexViewer
+	list    *widget.List
+	offset  *widget.Entry
+	mode    *widget.Select
+	search  *widget.Entry
+	status  *widget.Label
+	content *fyne.Container
+	objects []fyne.CanvasObject
+}
+
+func (r *hexViewerRenderer) Destroy() {}
+
+func (r *hexViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *hexViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *hexViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *hexViewerRenderer) Refresh() {
+	size, eof := r.viewer.available()
+	r.viewer.lock.Lock()
+	status := r.viewer.status
+	r.viewer.lock.Unlock()
+	if status == "" {
+		if eof {
+			status = fmt.Sprintf("%d bytes", size)
+		} else {
+			status = fmt.Sprintf("%d+ bytes", size)
+		}
+	}
+	r.status.SetText(status)
+	r.list.Refresh()
+}
+
+// parseHexOffset parses a decimal offset, or a hexadecimal offset prefixed with "0x".
+func parseHexOffset(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	digits, base := s, 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits, base = s[2:], 16
+	}
+	offset, err := strconv.ParseInt(digits, base, 64)
+	if err != nil || offset < 0 || strings.HasPrefix(digits, "+") {
+		return 0, fmt.Errorf("Invalid offset: %s", s)
+	}
+	return offset, nil
+}
+
+// parseHexPattern parses the bytes to search for, either as hexadecimal digits (ignoring spaces and any "0x" prefix) or as text.
+func parseHexPattern(s, mode string) ([]byte, error) {
+	if mode == hexModeText {
+		return []byte(s), nil
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	s = strings.Join(strings.Fields(s), "")
+	if len(s)%2 != 0 {
+		return nil, errors.New("Hex pattern must have an even number of digits")
+	}
+	pattern, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hex pattern: %w", err)
+	}
+	return pattern, nil
+}
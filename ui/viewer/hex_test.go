@@ -0,0 +1,205 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+// hexData returns size bytes of zeros with the pattern written at each of the given offsets.
+func hexData(size int, pattern []byte, offsets ...int) []byte {
+	data := make([]byte, size)
+	for _, o := range offsets {
+		copy(data[o:], pattern)
+	}
+	return data
+}
+
+func TestParseHexOffset(t *testing.T) {
+	for name, tt := range map[string]struct {
+		given string
+		want  int64
+		err   bool
+	}{
+		"Decimal":          {"1024", 1024, false},
+		"Hex":              {"0x400", 1024, false},
+		"HexUpper":         {"0X1F", 31, false},
+		"Spaces":           {" 16 ", 16, false},
+		"LeadingZero":      {"010", 10, false},
+		"LeadingZeroEight": {"08", 8, false},
+		"Negative":         {"-1", 0, true},
+		"HexNegative":      {"0x-1", 0, true},
+		"Plus":             {"+1", 0, true},
+		"Empty":            {"", 0, true},
+		"Invalid":          {"0xzz", 0, true},
+		"Text":             {"abc", 0, true},
+		"Binary":           {"0b1", 0, true},
+		"Octal":            {"0o7", 0, true},
+		"Underscore":       {"1_0", 0, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseHexOffset(tt.given)
+			if tt.err {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseHexPattern(t *testing.T) {
+	for name, tt := range map[string]struct {
+		given string
+		mode  string
+		want  []byte
+		err   string
+	}{
+		"Hex":         {"cafe", hexModeHex, []byte{0xca, 0xfe}, ""},
+		"Hex_Prefix":  {"0XCAFE", hexModeHex, []byte{0xca, 0xfe}, ""},
+		"Hex_Spaces":  {" ca fe ba be ", hexModeHex, []byte{0xca, 0xfe, 0xba, 0xbe}, ""},
+		"Hex_Odd":     {"caf", hexModeHex, nil, "Hex pattern must have an even number of digits"},
+		"Hex_Invalid": {"zz", hexModeHex, nil, "Invalid hex pattern: encoding/hex: invalid byte: U+007A 'z'"},
+		"Text":        {"0x cafe", hexModeText, []byte("0x cafe"), ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseHexPattern(tt.given, tt.mode)
+			if tt.err == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHexViewer_Find(t *testing.T) {
+	test.NewApp()
+	pattern := []byte{0xde, 0xad, 0xbe, 0xef}
+	// Second match straddles the boundary between pages
+	data := hexData(3*hexPageSize, pattern, 100, hexPageSize-2)
+	for name, source := range map[string]func() io.Reader{
+		"Random": func() io.Reader {
+			return bytes.NewReader(data)
+		},
+		"Sequential": func() io.Reader {
+			// Hide ReaderAt and Seeker so pages are read as needed
+			return struct{ io.Reader }{bytes.NewReader(data)}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v := NewHexViewer()
+			assert.Nil(t, v.SetSource(source()))
+
+			offset, err := v.find(pattern, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(100), offset)
+
+			offset, err = v.find(pattern, 101)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(hexPageSize-2), offset)
+
+			offset, err = v.find(pattern, hexPageSize)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(-1), offset)
+
+			offset, err = v.find([]byte{0x01}, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(-1), offset)
+		})
+	}
+}
+
+func TestHexViewer_Reopen(t *testing.T) {
+	test.NewApp()
+	pattern := []byte{0xde, 0xad, 0xbe, 0xef}
+	data := hexData((hexMaxPages+4)*hexPageSize, pattern, 100, (hexMaxPages+2)*hexPageSize)
+	opened := 0
+	open := func() (io.Reader, error) {
+		opened++
+		return struct{ io.Reader }{bytes.NewReader(data)}, nil
+	}
+	reader, err := open()
+	assert.Nil(t, err)
+	v := NewHexViewer()
+	assert.Nil(t, v.SetSource(NewReopenableSource(reader, open)))
+
+	// Reading the whole file only keeps the pages near the last read
+	offset, err := v.find(pattern, 101)
+	assert.Nil(t, err)
+	assert.Equal(t, int64((hexMaxPages+2)*hexPageSize), offset)
+	assert.True(t, len(v.pages) <= hexMaxPages)
+	_, ok := v.pages[0]
+	assert.False(t, ok)
+	assert.Nil(t, v.load(int64(len(data))+1))
+	assert.True(t, len(v.pages) <= hexMaxPages)
+	length, eof := v.available()
+	assert.Equal(t, int64(len(data)), length)
+	assert.True(t, eof)
+
+	// Discarded pages are read again from the start
+	offset, err = v.find(pattern, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), offset)
+	assert.Equal(t, 2, opened)
+	assert.True(t, len(v.pages) <= hexMaxPages)
+
+	got, err := v.bytes(hexPageSize-2, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, data[hexPageSize-2:hexPageSize+2], got)
+	assert.Equal(t, 2, opened)
+}
+
+func TestHexViewer_Search(t *testing.T) {
+	test.NewApp()
+	pattern := []byte("needle")
+	v := NewHexViewer()
+	assert.Nil(t, v.SetSource(bytes.NewReader(hexData(1024, pattern, 16, 512))))
+
+	v.Search(pattern)
+	assert.Equal(t, int64(16), v.match)
+	assert.Equal(t, "Found at offset 0x10", v.status)
+
+	v.Search(pattern)
+	assert.Equal(t, int64(512), v.match)
+
+	// Wraps around to the first match
+	v.Search(pattern)
+	assert.Equal(t, int64(16), v.match)
+
+	v.Search([]byte("missing"))
+	assert.Equal(t, int64(-1), v.match)
+	assert.Equal(t, "Not found", v.status)
+}
+
+func TestHexViewer_Jump(t *testing.T) {
+	test.NewApp()
+	v := NewHexViewer()
+	assert.Nil(t, v.SetSource(struct{ io.Reader }{bytes.NewReader(make([]byte, 256))}))
+
+	v.Jump(0xff)
+	assert.Equal(t, "Offset 0xff", v.status)
+
+	v.Jump(0x100)
+	assert.Equal(t, "Offset 0x100 is beyond the end of the file (256 bytes)", v.status)
+}
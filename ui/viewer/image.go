@@ -18,31 +18,81 @@ package viewer
 
 import (
 	"aletheiaware.com/spacego"
+	"bytes"
+	"errors"
+	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 	"image"
+	"image/color"
+	"image/draw"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	MIME_TYPE_IMAGE_BMP  = "image/bmp"
+	MIME_TYPE_IMAGE_TIFF = "image/tiff"
+	MIME_TYPE_IMAGE_WEBP = "image/webp"
+)
+
+const (
+	imageZoomFactor = 1.25
+	imageZoomMax    = 32
+	imageZoomMin    = 1.0 / 32
+	imagePanStep    = 0.1
+
+	imageDetailsWidth = 250
 )
 
 func init() {
 	generator := func() (Viewer, error) {
 		return NewImageViewer(), nil
 	}
-	Register(spacego.MIME_TYPE_IMAGE_GIF, generator)
-	Register(spacego.MIME_TYPE_IMAGE_JPEG, generator)
-	Register(spacego.MIME_TYPE_IMAGE_JPG, generator)
-	// TODO	Register(spacego.MIME_TYPE_IMAGE_SVG, generator)
-	Register(spacego.MIME_TYPE_IMAGE_PNG, generator)
+	RegisterViewer(MIME_TYPE_IMAGE_BMP, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(spacego.MIME_TYPE_IMAGE_GIF, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(spacego.MIME_TYPE_IMAGE_JPEG, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(spacego.MIME_TYPE_IMAGE_JPG, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(spacego.MIME_TYPE_IMAGE_PNG, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(MIME_TYPE_IMAGE_TIFF, "Image", PRIORITY_DEFAULT, generator)
+	RegisterViewer(MIME_TYPE_IMAGE_WEBP, "Image", PRIORITY_DEFAULT, generator)
+	// Attempt to decode any other image
+	RegisterViewer("image/*", "Image", PRIORITY_DEFAULT, generator)
 }
 
+// ImageViewer displays a raster image which can be zoomed, panned, and rotated.
+// Animated images play automatically and can be paused.
+// Keyboard shortcuts are + and - to zoom, 0 to fit, 1 for actual size, r and l to rotate, space to play or pause, and arrow keys to pan.
 type ImageViewer struct {
 	widget.BaseWidget
-	img image.Image
+	// source is the decoded image, corrected for its EXIF orientation
+	source image.Image
+	// img is the source rotated by the given number of clockwise quarter turns
+	img      image.Image
+	rotation int
+	// details describe the image's format and metadata
+	details     []*imageDetail
+	showDetails bool
+	// zoom is the number of screen pixels per image pixel, or 0 to fit the available space
+	zoom     float32
+	focused  bool
+	scroller *container.Scroll
+	// animation holds the frames of an animated image
+	animation *imageAnimation
+	lock      sync.Mutex
 }
 
 func NewImageViewer() *ImageViewer {
@@ -56,11 +106,42 @@ func (v *ImageViewer) CreateRenderer() fyne.WidgetRenderer {
 	r := &imageViewerRenderer{
 		viewer: v,
 		img: &canvas.Image{
-			FillMode: canvas.ImageFillOriginal,
+			FillMode: canvas.ImageFillContain,
 		},
 	}
 	r.scroller = container.NewScroll(r.img)
-	r.objects = []fyne.CanvasObject{r.scroller}
+	v.lock.Lock()
+	v.scroller = r.scroller
+	v.lock.Unlock()
+	r.details = container.NewVScroll(widget.NewForm())
+	r.details.SetMinSize(fyne.NewSize(imageDetailsWidth, 0))
+	overlay := &imageViewerOverlay{
+		viewer: v,
+	}
+	overlay.ExtendBaseWidget(overlay)
+	r.play = widget.NewButtonWithIcon("", theme.MediaPauseIcon(), v.TogglePlayback)
+	r.play.Hide()
+	controls := container.NewHBox(
+		r.play,
+		widget.NewButtonWithIcon("", theme.ZoomFitIcon(), func() {
+			v.SetZoom(0)
+		}),
+		widget.NewButton("1:1", func() {
+			v.SetZoom(1)
+		}),
+		widget.NewButtonWithIcon("", theme.ZoomOutIcon(), v.ZoomOut),
+		widget.NewButtonWithIcon("", theme.ZoomInIcon(), v.ZoomIn),
+		widget.NewButtonWithIcon("", theme.ContentUndoIcon(), func() {
+			v.Rotate(-1)
+		}),
+		widget.NewButtonWithIcon("", theme.ContentRedoIcon(), func() {
+			v.Rotate(1)
+		}),
+		layout.NewSpacer(),
+		widget.NewButtonWithIcon("", theme.InfoIcon(), v.ToggleDetails),
+	)
+	r.content = container.NewBorder(controls, nil, nil, r.details, container.NewMax(r.scroller, overlay))
+	r.objects = []fyne.CanvasObject{r.content}
 	return r
 }
 
@@ -70,30 +151,212 @@ func (v *ImageViewer) MinSize() fyne.Size {
 }
 
 func (v *ImageViewer) SetSource(source io.Reader) error {
-	i, _, err := image.Decode(source)
+	data, err := ioutil.ReadAll(source)
 	if err != nil {
 		return err
 	}
-	v.img = i
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	var i image.Image
+	var animation *imageAnimation
+	var animationErr error
+	if format == "gif" {
+		// Decode every frame at once, rather than the first frame and then again for the animation
+		i, animation, animationErr = decodeGIF(data)
+		if animationErr != nil && !errors.Is(animationErr, errAnimationTooLarge) {
+			return animationErr
+		}
+	} else {
+		i, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+	}
+	exif, err := readExif(data)
+	if err != nil {
+		exif = &exifData{}
+	}
+	details := imageDetails(i, format, exif)
+	switch {
+	case animationErr != nil:
+		// Show the first frame instead
+		details = append(details, &imageDetail{"Animation", animationErr.Error()})
+	case animation != nil:
+		i = animation.frames[0]
+		details = append(details, animation.details()...)
+	}
+	i = orientImage(i, exif.orientation())
+	v.Stop()
+	v.lock.Lock()
+	v.source = i
+	v.details = details
+	v.img = rotateImage(i, v.rotation)
+	v.animation = animation
+	v.lock.Unlock()
 	v.Refresh()
+	if animation != nil {
+		v.Play()
+	}
 	return nil
 }
 
+// ToggleDetails shows or hides the panel describing the image's format and metadata.
+func (v *ImageViewer) ToggleDetails() {
+	v.lock.Lock()
+	v.showDetails = !v.showDetails
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// Rotate turns the image by the given number of clockwise quarter turns, negative turns are anticlockwise.
+func (v *ImageViewer) Rotate(turns int) {
+	v.lock.Lock()
+	v.rotation = ((v.rotation+turns)%4 + 4) % 4
+	if v.source != nil {
+		v.img = rotateImage(v.source, v.rotation)
+	}
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// SetZoom sets the number of screen pixels per image pixel, or 0 to fit the available space.
+func (v *ImageViewer) SetZoom(zoom float32) {
+	if zoom != 0 {
+		zoom = float32(math.Max(imageZoomMin, math.Min(imageZoomMax, float64(zoom))))
+	}
+	v.lock.Lock()
+	previous := v.effectiveZoom()
+	v.zoom = zoom
+	scroller := v.scroller
+	v.lock.Unlock()
+	v.Refresh()
+	if scroller == nil || previous == 0 || zoom == 0 {
+		return
+	}
+	// Keep the center of the view in place
+	ratio := zoom / previous
+	size := scroller.Size()
+	scroller.Offset = fyne.NewPos((scroller.Offset.X+size.Width/2)*ratio-size.Width/2, (scroller.Offset.Y+size.Height/2)*ratio-size.Height/2)
+	scroller.Refresh()
+}
+
+func (v *ImageViewer) ZoomIn() {
+	v.lock.Lock()
+	zoom := v.effectiveZoom()
+	v.lock.Unlock()
+	v.SetZoom(zoom * imageZoomFactor)
+}
+
+func (v *ImageViewer) ZoomOut() {
+	v.lock.Lock()
+	zoom := v.effectiveZoom()
+	v.lock.Unlock()
+	v.SetZoom(zoom / imageZoomFactor)
+}
+
+// Pan moves the view of the image by the given distance.
+func (v *ImageViewer) Pan(dx, dy float32) {
+	v.lock.Lock()
+	scroller := v.scroller
+	v.lock.Unlock()
+	if scroller == nil {
+		return
+	}
+	scroller.Offset = fyne.NewPos(scroller.Offset.X+dx, scroller.Offset.Y+dy)
+	scroller.Refresh()
+}
+
+func (v *ImageViewer) FocusGained() {
+	v.focused = true
+}
+
+func (v *ImageViewer) FocusLost() {
+	v.focused = false
+}
+
+func (v *ImageViewer) Focused() bool {
+	return v.focused
+}
+
+func (v *ImageViewer) TypedRune(r rune) {
+	switch r {
+	case '+', '=':
+		v.ZoomIn()
+	case '-', '_':
+		v.ZoomOut()
+	case '0':
+		v.SetZoom(0)
+	case '1':
+		v.SetZoom(1)
+	case 'r', 'R':
+		v.Rotate(1)
+	case 'l', 'L':
+		v.Rotate(-1)
+	case ' ':
+		v.TogglePlayback()
+	}
+}
+
+func (v *ImageViewer) TypedKey(event *fyne.KeyEvent) {
+	size := v.Size()
+	switch event.Name {
+	case fyne.KeyUp:
+		v.Pan(0, -size.Height*imagePanStep)
+	case fyne.KeyDown:
+		v.Pan(0, size.Height*imagePanStep)
+	case fyne.KeyLeft:
+		v.Pan(-size.Width*imagePanStep, 0)
+	case fyne.KeyRight:
+		v.Pan(size.Width*imagePanStep, 0)
+	}
+}
+
+// effectiveZoom returns the zoom, calculating the zoom needed to fit the available space if necessary, the caller must hold the lock.
+func (v *ImageViewer) effectiveZoom() float32 {
+	if v.zoom != 0 || v.img == nil || v.scroller == nil {
+		return v.zoom
+	}
+	b := v.img.Bounds()
+	if b.Empty() {
+		return 1
+	}
+	size := v.scroller.Size()
+	scale := canvasScale(v)
+	return float32(math.Min(float64(size.Width*scale)/float64(b.Dx()), float64(size.Height*scale)/float64(b.Dy())))
+}
+
+// canvasScale returns the number of pixels per unit of the canvas showing the object.
+func canvasScale(o fyne.CanvasObject) float32 {
+	if app := fyne.CurrentApp(); app != nil {
+		if c := app.Driver().CanvasForObject(o); c != nil {
+			return c.Scale()
+		}
+	}
+	return 1
+}
+
 type imageViewerRenderer struct {
 	viewer   *ImageViewer
 	img      *canvas.Image
 	scroller *container.Scroll
+	details  *container.Scroll
+	play     *widget.Button
+	content  *fyne.Container
 	objects  []fyne.CanvasObject
+	// shown holds the details currently displayed in the panel
+	shown []*imageDetail
 }
 
 func (r *imageViewerRenderer) Destroy() {}
 
 func (r *imageViewerRenderer) Layout(size fyne.Size) {
-	r.scroller.Resize(size)
+	r.content.Resize(size)
 }
 
 func (r *imageViewerRenderer) MinSize() fyne.Size {
-	return r.scroller.MinSize()
+	return r.content.MinSize()
 }
 
 func (r *imageViewerRenderer) Objects() []fyne.CanvasObject {
@@ -101,7 +364,284 @@ func (r *imageViewerRenderer) Objects() []fyne.CanvasObject {
 }
 
 func (r *imageViewerRenderer) Refresh() {
-	r.img.Image = r.viewer.img
+	r.viewer.lock.Lock()
+	img := r.viewer.img
+	zoom := r.viewer.zoom
+	details := r.viewer.details
+	showDetails := r.viewer.showDetails
+	animated := r.viewer.animation != nil
+	playing := animated && r.viewer.animation.stop != nil
+	r.viewer.lock.Unlock()
+	if !animated {
+		r.play.Hide()
+	} else {
+		if playing {
+			r.play.SetIcon(theme.MediaPauseIcon())
+		} else {
+			r.play.SetIcon(theme.MediaPlayIcon())
+		}
+		r.play.Show()
+	}
+	if showDetails {
+		if len(details) != len(r.shown) || (len(details) > 0 && details[0] != r.shown[0]) {
+			form := widget.NewForm()
+			for _, d := range details {
+				form.Append(d.name, &widget.Label{
+					Text:     d.value,
+					Wrapping: fyne.TextWrapWord,
+				})
+			}
+			r.details.Content = form
+			r.shown = details
+		}
+		r.details.Show()
+		r.details.Refresh()
+	} else {
+		r.details.Hide()
+	}
+	r.content.Refresh()
+	r.img.Image = img
+	// The scroller expands the image to fill its viewport, so contain keeps the aspect ratio and centers smaller images
+	if img == nil || zoom == 0 {
+		r.img.SetMinSize(fyne.NewSize(1, 1))
+	} else {
+		b := img.Bounds()
+		scale := canvasScale(r.viewer)
+		r.img.SetMinSize(fyne.NewSize(float32(b.Dx())*zoom/scale, float32(b.Dy())*zoom/scale))
+	}
 	r.img.Refresh()
 	r.scroller.Refresh()
 }
+
+// imageDetail is a named property of an image.
+type imageDetail struct {
+	name  string
+	value string
+}
+
+// imageDetails describes the format, dimensions, colour model, and EXIF metadata of the decoded image.
+func imageDetails(img image.Image, format string, exif *exifData) []*imageDetail {
+	b := img.Bounds()
+	details := []*imageDetail{
+		{"Format", strings.ToUpper(format)},
+		{"Dimensions", fmt.Sprintf("%d × %d pixels", b.Dx(), b.Dy())},
+		{"Colour Model", colorModelName(img)},
+	}
+	add := func(name string, entries map[uint16]*exifEntry, tag uint16) {
+		if e, ok := entries[tag]; ok {
+			if s := e.String(); s != "" {
+				details = append(details, &imageDetail{name, s})
+			}
+		}
+	}
+	camera := strings.TrimSpace(exifString(exif.ifd0, EXIF_TAG_MAKE) + " " + exifString(exif.ifd0, EXIF_TAG_MODEL))
+	if camera != "" {
+		details = append(details, &imageDetail{"Camera", camera})
+	}
+	add("Lens", exif.exif, EXIF_TAG_LENS_MODEL)
+	if e, ok := exif.exif[EXIF_TAG_DATE_TIME_ORIGINAL]; ok {
+		details = append(details, &imageDetail{"Date Taken", e.String()})
+	} else {
+		add("Date", exif.ifd0, EXIF_TAG_DATE_TIME)
+	}
+	if e, ok := exif.exif[EXIF_TAG_EXPOSURE_TIME]; ok {
+		if t, ok := e.rational(0); ok && t > 0 {
+			if t < 1 {
+				details = append(details, &imageDetail{"Exposure", fmt.Sprintf("1/%.0f s", 1/t)})
+			} else {
+				details = append(details, &imageDetail{"Exposure", fmt.Sprintf("%g s", t)})
+			}
+		}
+	}
+	if e, ok := exif.exif[EXIF_TAG_F_NUMBER]; ok {
+		if n, ok := e.rational(0); ok {
+			details = append(details, &imageDetail{"Aperture", fmt.Sprintf("f/%.1f", n)})
+		}
+	}
+	add("ISO", exif.exif, EXIF_TAG_ISO)
+	if e, ok := exif.exif[EXIF_TAG_FOCAL_LENGTH]; ok {
+		if l, ok := e.rational(0); ok {
+			details = append(details, &imageDetail{"Focal Length", fmt.Sprintf("%g mm", l)})
+		}
+	}
+	if _, ok := exif.ifd0[EXIF_TAG_ORIENTATION]; ok {
+		details = append(details, &imageDetail{"Orientation", orientationNames[exif.orientation()]})
+	}
+	if lat, ok := exif.coordinate(EXIF_GPS_LATITUDE, EXIF_GPS_LATITUDE_REF); ok {
+		if lon, ok := exif.coordinate(EXIF_GPS_LONGITUDE, EXIF_GPS_LONGITUDE_REF); ok {
+			details = append(details, &imageDetail{"Location", fmt.Sprintf("%.6f, %.6f", lat, lon)})
+		}
+	}
+	if a, ok := exif.altitude(); ok {
+		details = append(details, &imageDetail{"Altitude", fmt.Sprintf("%.1f m", a)})
+	}
+	add("Software", exif.ifd0, EXIF_TAG_SOFTWARE)
+	return details
+}
+
+func exifString(entries map[uint16]*exifEntry, tag uint16) string {
+	if e, ok := entries[tag]; ok {
+		return e.String()
+	}
+	return ""
+}
+
+// orientationNames describes the transformation needed to display an image upright, indexed by EXIF orientation.
+var orientationNames = []string{
+	"",
+	"Normal",
+	"Mirror horizontal",
+	"Rotate 180°",
+	"Mirror vertical",
+	"Mirror horizontal and rotate 270° clockwise",
+	"Rotate 90° clockwise",
+	"Mirror horizontal and rotate 90° clockwise",
+	"Rotate 270° clockwise",
+}
+
+// colorModelName returns a description of the image's colour model.
+func colorModelName(img image.Image) string {
+	switch i := img.(type) {
+	case *image.YCbCr:
+		return "YCbCr " + map[image.YCbCrSubsampleRatio]string{
+			image.YCbCrSubsampleRatio444: "4:4:4",
+			image.YCbCrSubsampleRatio422: "4:2:2",
+			image.YCbCrSubsampleRatio420: "4:2:0",
+			image.YCbCrSubsampleRatio440: "4:4:0",
+			image.YCbCrSubsampleRatio411: "4:1:1",
+			image.YCbCrSubsampleRatio410: "4:1:0",
+		}[i.SubsampleRatio]
+	case *image.Paletted:
+		return fmt.Sprintf("Paletted (%d colours)", len(i.Palette))
+	}
+	switch img.ColorModel() {
+	case color.AlphaModel:
+		return "Alpha"
+	case color.Alpha16Model:
+		return "Alpha 16-bit"
+	case color.CMYKModel:
+		return "CMYK"
+	case color.GrayModel:
+		return "Gray"
+	case color.Gray16Model:
+		return "Gray 16-bit"
+	case color.NRGBAModel:
+		return "NRGBA"
+	case color.NRGBA64Model:
+		return "NRGBA 64-bit"
+	case color.RGBAModel:
+		return "RGBA"
+	case color.RGBA64Model:
+		return "RGBA 64-bit"
+	}
+	return fmt.Sprintf("%T", img)
+}
+
+// imageViewerOverlay lies over the image to zoom with the scroll wheel and pan by dragging.
+type imageViewerOverlay struct {
+	widget.BaseWidget
+	viewer *ImageViewer
+}
+
+func (o *imageViewerOverlay) CreateRenderer() fyne.WidgetRenderer {
+	return &imageViewerOverlayRenderer{}
+}
+
+func (o *imageViewerOverlay) Scrolled(event *fyne.ScrollEvent) {
+	if event.Scrolled.DY > 0 {
+		o.viewer.ZoomIn()
+	} else if event.Scrolled.DY < 0 {
+		o.viewer.ZoomOut()
+	}
+}
+
+func (o *imageViewerOverlay) Dragged(event *fyne.DragEvent) {
+	o.viewer.Pan(-event.Dragged.DX, -event.Dragged.DY)
+}
+
+func (o *imageViewerOverlay) DragEnd() {}
+
+func (o *imageViewerOverlay) Tapped(*fyne.PointEvent) {
+	if c := fyne.CurrentApp().Driver().CanvasForObject(o.viewer); c != nil {
+		c.Focus(o.viewer)
+	}
+}
+
+// imageViewerOverlayRenderer draws nothing so the image beneath remains visible.
+type imageViewerOverlayRenderer struct{}
+
+func (r *imageViewerOverlayRenderer) Destroy() {}
+
+func (r *imageViewerOverlayRenderer) Layout(fyne.Size) {}
+
+func (r *imageViewerOverlayRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *imageViewerOverlayRenderer) Objects() []fyne.CanvasObject {
+	return nil
+}
+
+func (r *imageViewerOverlayRenderer) Refresh() {}
+
+// orientImage transforms the image according to its EXIF orientation so that it displays upright.
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	// Orientations 5 to 8 swap the width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirror horizontally
+				sx, sy = w-1-x, y
+			case 3: // Rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirror vertically
+				sx, sy = x, h-1-y
+			case 5: // Transpose
+				sx, sy = y, x
+			case 6: // Rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // Transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // Rotate 90 anticlockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// rotateImage turns the image by the given number of clockwise quarter turns.
+func rotateImage(img image.Image, turns int) image.Image {
+	switch turns {
+	case 1:
+		return orientImage(img, 6)
+	case 2:
+		return orientImage(img, 3)
+	case 3:
+		return orientImage(img, 8)
+	}
+	return img
+}
+
+// toNRGBA converts the image to NRGBA with its bounds starting at the origin.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Bounds().Min == (image.Point{}) {
+		return n
+	}
+	b := img.Bounds()
+	n := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(n, n.Bounds(), img, b.Min, draw.Src)
+	return n
+}
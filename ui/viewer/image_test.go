@@ -0,0 +1,196 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// Base64 encoded images, the BMP and TIFF have a red pixel to the left of a blue pixel, and the WebP is a single pixel.
+const (
+	bmpFixture  = "Qk0+AAAAAAAAADYAAAAoAAAAAgAAAAEAAAABABgAAAAAAAgAAAAAAAAAAAAAAAAAAAAAAAAAAAD//wAAAAA="
+	tiffFixture = "SUkqABAAAAD/AAD/AAD//w0AAAEDAAEAAAACAAAAAQEDAAEAAAABAAAAAgEDAAQAAACyAAAAAwEDAAEAAAABAAAABgEDAAEAAAACAAAAEQEEAAEAAAAIAAAAFQEDAAEAAAAEAAAAFgEDAAEAAAABAAAAFwEEAAEAAAAIAAAAGgEFAAEAAAC6AAAAGwEFAAEAAADCAAAAKAEDAAEAAAACAAAAUgEDAAEAAAACAAAAAAAAAAgACAAIAAgASAAAAAEAAABIAAAAAQAAAA=="
+	webpFixture = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+)
+
+// newTestImage returns a 3x2 image whose pixels have red values of 1 to 6, row by row.
+//
+//	1 2 3
+//	4 5 6
+func newTestImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(y*3 + x + 1), A: 0xff})
+		}
+	}
+	return img
+}
+
+// pixels returns the red values of the image's pixels, row by row.
+func pixels(img image.Image) [][]uint8 {
+	b := img.Bounds()
+	var rows [][]uint8
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		var row []uint8
+		for x := b.Min.X; x < b.Max.X; x++ {
+			row = append(row, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).R)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestOrientImage(t *testing.T) {
+	for name, tt := range map[string]struct {
+		orientation int
+		want        [][]uint8
+	}{
+		"0_Unknown":    {0, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		"1_Normal":     {1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		"2_Mirror":     {2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		"3_Rotate180":  {3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		"4_Flip":       {4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		"5_Transpose":  {5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		"6_Rotate90":   {6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		"7_Transverse": {7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		"8_Rotate270":  {8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		"9_Invalid":    {9, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pixels(orientImage(newTestImage(), tt.orientation)))
+		})
+	}
+	t.Run("Offset", func(t *testing.T) {
+		// Images whose bounds don't start at the origin, such as sub images, are still transformed
+		img := newTestImage().(*image.NRGBA).SubImage(image.Rect(1, 0, 3, 2))
+		assert.Equal(t, [][]uint8{{5, 2}, {6, 3}}, pixels(orientImage(img, 6)))
+	})
+}
+
+func TestImageViewer_Rotate(t *testing.T) {
+	v := newTestImageViewer(t)
+	for _, tt := range []struct {
+		turns    int
+		rotation int
+		want     [][]uint8
+	}{
+		{1, 1, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{1, 2, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{-3, 3, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{5, 0, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{-1, 3, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{-7, 0, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+	} {
+		v.Rotate(tt.turns)
+		assert.Equal(t, tt.rotation, v.rotation)
+		assert.Equal(t, tt.want, pixels(v.img))
+	}
+}
+
+func TestImageViewer_SetZoom(t *testing.T) {
+	v := newTestImageViewer(t)
+	for name, tt := range map[string]struct {
+		zoom float32
+		want float32
+	}{
+		"Fit":      {0, 0},
+		"Actual":   {1, 1},
+		"Max":      {imageZoomMax, imageZoomMax},
+		"Min":      {imageZoomMin, imageZoomMin},
+		"TooBig":   {1000, imageZoomMax},
+		"TooSmall": {0.0001, imageZoomMin},
+		"Negative": {-2, imageZoomMin},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v.SetZoom(tt.zoom)
+			assert.Equal(t, tt.want, v.zoom)
+		})
+	}
+	t.Run("ZoomIn", func(t *testing.T) {
+		v.SetZoom(imageZoomMax)
+		v.ZoomIn()
+		assert.Equal(t, float32(imageZoomMax), v.zoom)
+		v.SetZoom(1)
+		v.ZoomIn()
+		assert.Equal(t, float32(imageZoomFactor), v.zoom)
+	})
+	t.Run("ZoomOut", func(t *testing.T) {
+		v.SetZoom(imageZoomMin)
+		v.ZoomOut()
+		assert.Equal(t, float32(imageZoomMin), v.zoom)
+		v.SetZoom(1)
+		v.ZoomOut()
+		assert.Equal(t, float32(1/imageZoomFactor), v.zoom)
+	})
+}
+
+func TestImageViewer_SetSource_Formats(t *testing.T) {
+	// Fixtures are decoded without importing their encoders, so they fail if the decoders aren't registered by image.go
+	for name, tt := range map[string]struct {
+		data   string
+		format string
+		width  int
+		height int
+	}{
+		"BMP":  {bmpFixture, "bmp", 2, 1},
+		"TIFF": {tiffFixture, "tiff", 2, 1},
+		"WebP": {webpFixture, "webp", 1, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := base64.StdEncoding.DecodeString(tt.data)
+			assert.Nil(t, err)
+			img, format, err := image.Decode(bytes.NewReader(data))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.format, format)
+			assert.Equal(t, image.Rect(0, 0, tt.width, tt.height), img.Bounds())
+			v := NewImageViewer()
+			assert.Nil(t, v.SetSource(bytes.NewReader(data)))
+			assert.Equal(t, img.Bounds(), v.img.Bounds())
+		})
+	}
+	t.Run("Pixels", func(t *testing.T) {
+		// Left pixel is red and right is blue
+		for _, d := range []string{
+			bmpFixture,
+			tiffFixture,
+		} {
+			data, err := base64.StdEncoding.DecodeString(d)
+			assert.Nil(t, err)
+			img, _, err := image.Decode(bytes.NewReader(data))
+			assert.Nil(t, err)
+			assert.Equal(t, color.NRGBA{R: 0xff, A: 0xff}, color.NRGBAModel.Convert(img.At(0, 0)))
+			assert.Equal(t, color.NRGBA{B: 0xff, A: 0xff}, color.NRGBAModel.Convert(img.At(1, 0)))
+		}
+	})
+}
+
+// newTestImageViewer returns an ImageViewer showing the test image.
+func newTestImageViewer(t *testing.T) *ImageViewer {
+	t.Helper()
+	var buffer bytes.Buffer
+	assert.Nil(t, png.Encode(&buffer, newTestImage()))
+	v := NewImageViewer()
+	assert.Nil(t, v.SetSource(&buffer))
+	return v
+}
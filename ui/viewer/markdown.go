@@ -0,0 +1,522 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MIME_TYPE_TEXT_MARKDOWN = "text/markdown"
+
+func init() {
+	RegisterViewer(MIME_TYPE_TEXT_MARKDOWN, "Markdown", PRIORITY_DEFAULT, func() (Viewer, error) {
+		return NewMarkdownViewer(), nil
+	})
+}
+
+type markdownBlockKind int
+
+const (
+	markdownParagraph markdownBlockKind = iota
+	markdownHeading
+	markdownListItem
+	markdownQuote
+	markdownCode
+	markdownRule
+)
+
+// markdownBlock is a block level element of a Markdown document.
+type markdownBlock struct {
+	kind markdownBlockKind
+	// level is the heading level, or the nesting depth of a list item
+	level int
+	// bullet is the marker of a list item
+	bullet string
+	text   string
+}
+
+// markdownSpan is a run of inline text sharing the same style.
+type markdownSpan struct {
+	text  string
+	style fyne.TextStyle
+	link  string
+}
+
+type MarkdownViewer struct {
+	widget.BaseWidget
+	blocks []*markdownBlock
+}
+
+func NewMarkdownViewer() *MarkdownViewer {
+	v := &MarkdownViewer{}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *MarkdownViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &markdownViewerRenderer{
+		viewer: v,
+		box:    container.NewVBox(),
+	}
+	r.scroller = container.NewVScroll(r.box)
+	r.objects = []fyne.CanvasObject{r.scroller}
+	return r
+}
+
+func (v *MarkdownViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+func (v *MarkdownViewer) SetSource(source io.Reader) error {
+	bytes, err := ioutil.ReadAll(source)
+	if err != nil {
+		return err
+	}
+	v.blocks = parseMarkdown(string(bytes))
+	v.Refresh()
+	return nil
+}
+
+type markdownViewerRenderer struct {
+	viewer   *MarkdownViewer
+	box      *fyne.Container
+	scroller *container.Scroll
+	objects  []fyne.CanvasObject
+}
+
+func (r *markdownViewerRenderer) Destroy() {}
+
+func (r *markdownViewerRenderer) Layout(size fyne.Size) {
+	r.scroller.Resize(size)
+}
+
+func (r *markdownViewerRenderer) MinSize() fyne.Size {
+	return r.scroller.MinSize()
+}
+
+func (r *markdownViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *markdownViewerRenderer) Refresh() {
+	// Paragraphs wrap to the width of the scroller, which is set before its contents are laid out
+	width := func() float32 {
+		return r.scroller.Size().Width
+	}
+	var objects []fyne.CanvasObject
+	for _, b := range r.viewer.blocks {
+		objects = append(objects, renderMarkdownBlock(b, width))
+	}
+	r.box.Objects = objects
+	r.box.Refresh()
+	r.scroller.Refresh()
+}
+
+// parseMarkdown splits the document into block level elements.
+func parseMarkdown(text string) []*markdownBlock {
+	var (
+		blocks    []*markdownBlock
+		paragraph []string
+		code      []string
+		fenced    bool
+		quoted    bool
+	)
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, &markdownBlock{
+				kind: markdownParagraph,
+				text: strings.Join(paragraph, " "),
+			})
+			paragraph = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		continuing := quoted
+		quoted = false
+		if fenced {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				blocks = append(blocks, &markdownBlock{
+					kind: markdownCode,
+					text: strings.Join(code, "\n"),
+				})
+				code = nil
+				fenced = false
+			} else {
+				code = append(code, line)
+			}
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fenced = true
+		case isMarkdownRule(trimmed):
+			flush()
+			blocks = append(blocks, &markdownBlock{
+				kind: markdownRule,
+			})
+		case strings.HasPrefix(trimmed, "#"):
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if level > 6 || (len(trimmed) > level && trimmed[level] != ' ') {
+				paragraph = append(paragraph, trimmed)
+				continue
+			}
+			flush()
+			blocks = append(blocks, &markdownBlock{
+				kind:  markdownHeading,
+				level: level,
+				text:  strings.TrimSpace(strings.TrimRight(trimmed[level:], "#")),
+			})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			quoted = true
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			// Consecutive lines form a single quote
+			if l := len(blocks); continuing && l > 0 && blocks[l-1].kind == markdownQuote {
+				if quote != "" {
+					blocks[l-1].text = strings.TrimSpace(blocks[l-1].text + " " + quote)
+				}
+				continue
+			}
+			blocks = append(blocks, &markdownBlock{
+				kind: markdownQuote,
+				text: quote,
+			})
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "), strings.HasPrefix(trimmed, "+ "):
+			flush()
+			blocks = append(blocks, &markdownBlock{
+				kind:   markdownListItem,
+				level:  indent / 2,
+				bullet: "•",
+				text:   strings.TrimSpace(trimmed[2:]),
+			})
+		default:
+			if n, rest, ok := markdownOrderedItem(trimmed); ok {
+				flush()
+				blocks = append(blocks, &markdownBlock{
+					kind:   markdownListItem,
+					level:  indent / 2,
+					bullet: n + ".",
+					text:   rest,
+				})
+				continue
+			}
+			if indent >= 4 && len(paragraph) == 0 {
+				// Indented code block
+				if l := len(blocks); l > 0 && blocks[l-1].kind == markdownCode && blocks[l-1].level == 1 {
+					blocks[l-1].text += "\n" + line[4:]
+				} else {
+					blocks = append(blocks, &markdownBlock{
+						kind:  markdownCode,
+						level: 1,
+						text:  line[4:],
+					})
+				}
+				continue
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	if fenced {
+		// Unterminated code block
+		blocks = append(blocks, &markdownBlock{
+			kind: markdownCode,
+			text: strings.Join(code, "\n"),
+		})
+	}
+	flush()
+	return blocks
+}
+
+func isMarkdownRule(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 {
+		return false
+	}
+	for _, c := range []string{"-", "*", "_"} {
+		if strings.Trim(line, c) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func markdownOrderedItem(line string) (string, string, bool) {
+	i := strings.IndexAny(line, ".)")
+	if i <= 0 || i+1 >= len(line) || line[i+1] != ' ' {
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(line[:i]); err != nil {
+		return "", "", false
+	}
+	return line[:i], strings.TrimSpace(line[i+1:]), true
+}
+
+// parseMarkdownInline splits the text into spans of emphasis, strong, code, and links.
+func parseMarkdownInline(text string) []*markdownSpan {
+	var (
+		spans   []*markdownSpan
+		current strings.Builder
+		style   fyne.TextStyle
+	)
+	flush := func() {
+		if current.Len() > 0 {
+			spans = append(spans, &markdownSpan{
+				text:  current.String(),
+				style: style,
+			})
+			current.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_[]()#+-.!", text[i+1]) >= 0:
+			i++
+			current.WriteByte(text[i])
+		case c == '`':
+			end := strings.IndexByte(text[i+1:], '`')
+			if end < 0 {
+				current.WriteByte(c)
+				continue
+			}
+			flush()
+			spans = append(spans, &markdownSpan{
+				text: text[i+1 : i+1+end],
+				style: fyne.TextStyle{
+					Monospace: true,
+				},
+			})
+			i += end + 1
+		case c == '[':
+			close := strings.Index(text[i:], "](")
+			if close < 0 {
+				current.WriteByte(c)
+				continue
+			}
+			end := strings.IndexByte(text[i+close:], ')')
+			if end < 0 {
+				current.WriteByte(c)
+				continue
+			}
+			flush()
+			spans = append(spans, &markdownSpan{
+				text:  text[i+1 : i+close],
+				style: style,
+				link:  text[i+close+2 : i+close+end],
+			})
+			i += close + end
+		case c == '*' || c == '_':
+			n := markdownDelimiterLength(text, i)
+			on := style.Italic
+			if n == 2 {
+				on = style.Bold
+			}
+			// Only toggle if closing the open emphasis, or opening one that is later closed
+			if (on && canCloseMarkdown(text, i, n)) || (!on && canOpenMarkdown(text, i, n) && hasMarkdownCloser(text, i+n, c, n)) {
+				flush()
+				if n == 2 {
+					style.Bold = !style.Bold
+				} else {
+					style.Italic = !style.Italic
+				}
+			} else {
+				current.WriteString(text[i : i+n])
+			}
+			i += n - 1
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return spans
+}
+
+// markdownDelimiterLength returns 2 if the emphasis delimiter at the index is doubled for strong, otherwise 1.
+func markdownDelimiterLength(text string, i int) int {
+	if i+1 < len(text) && text[i+1] == text[i] {
+		return 2
+	}
+	return 1
+}
+
+// canOpenMarkdown returns true if the delimiter of length n at the index is followed by a non-space, so "2 * 3" is not emphasized.
+// Underscores must also not be within a word, so snake_case is not emphasized.
+func canOpenMarkdown(text string, i, n int) bool {
+	after, _ := utf8.DecodeRuneInString(text[i+n:])
+	if after == utf8.RuneError || unicode.IsSpace(after) {
+		return false
+	}
+	return text[i] != '_' || i == 0 || !isWordByte(text[i-1])
+}
+
+// canCloseMarkdown returns true if the delimiter of length n at the index is preceded by a non-space.
+// Underscores must also not be within a word.
+func canCloseMarkdown(text string, i, n int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	if before == utf8.RuneError || unicode.IsSpace(before) {
+		return false
+	}
+	return text[i] != '_' || i+n >= len(text) || !isWordByte(text[i+n])
+}
+
+// hasMarkdownCloser returns true if the text from the index contains a delimiter of c with length n that can close emphasis.
+func hasMarkdownCloser(text string, i int, c byte, n int) bool {
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case c:
+			l := markdownDelimiterLength(text, i)
+			if l == n && canCloseMarkdown(text, i, n) {
+				return true
+			}
+			i += l - 1
+		}
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b >= 0x80
+}
+
+// renderMarkdownBlock returns the block, wrapping text to fit the width returned by the given function.
+func renderMarkdownBlock(b *markdownBlock, width func() float32) fyne.CanvasObject {
+	switch b.kind {
+	case markdownHeading:
+		scale := []float32{2, 1.6, 1.3, 1.15, 1, 0.9}[b.level-1]
+		return renderMarkdownInline(b.text, theme.TextSize()*scale, fyne.TextStyle{
+			Bold: true,
+		}, width)
+	case markdownListItem:
+		bullet := canvas.NewText(b.bullet+" ", theme.TextColor())
+		indent := canvas.NewRectangle(color.Transparent)
+		indent.SetMinSize(fyne.NewSize(float32(b.level)*theme.TextSize()*2, 0))
+		left := container.NewHBox(indent, bullet)
+		return container.NewBorder(nil, nil, left, nil, renderMarkdownInline(b.text, theme.TextSize(), fyne.TextStyle{}, insetWidth(width, left)))
+	case markdownQuote:
+		bar := canvas.NewRectangle(theme.PrimaryColor())
+		bar.SetMinSize(fyne.NewSize(theme.Padding(), 0))
+		return container.NewBorder(nil, nil, bar, nil, renderMarkdownInline(b.text, theme.TextSize(), fyne.TextStyle{
+			Italic: true,
+		}, insetWidth(width, bar)))
+	case markdownCode:
+		background := canvas.NewRectangle(theme.InputBackgroundColor())
+		label := widget.NewLabelWithStyle(b.text, fyne.TextAlignLeading, fyne.TextStyle{
+			Monospace: true,
+		})
+		return container.NewMax(background, container.NewHScroll(label))
+	case markdownRule:
+		return widget.NewSeparator()
+	}
+	return renderMarkdownInline(b.text, theme.TextSize(), fyne.TextStyle{}, width)
+}
+
+// insetWidth returns the width remaining beside the leading object of a border container.
+func insetWidth(width func() float32, leading fyne.CanvasObject) func() float32 {
+	inset := leading.MinSize().Width + theme.Padding()
+	return func() float32 {
+		return width() - inset
+	}
+}
+
+// renderMarkdownInline lays out the words of the text, wrapping them to the available width.
+func renderMarkdownInline(text string, size float32, base fyne.TextStyle, width func() float32) fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	for _, s := range parseMarkdownInline(text) {
+		style := s.style
+		style.Bold = style.Bold || base.Bold
+		style.Italic = style.Italic || base.Italic
+		if s.link != "" {
+			if u, err := url.Parse(s.link); err == nil {
+				link := widget.NewHyperlink(s.text, u)
+				link.TextStyle = style
+				objects = append(objects, link)
+				continue
+			}
+		}
+		for _, word := range strings.Fields(s.text) {
+			t := canvas.NewText(word+" ", theme.TextColor())
+			t.TextSize = size
+			t.TextStyle = style
+			objects = append(objects, t)
+		}
+	}
+	return container.New(&flowLayout{width: width}, objects...)
+}
+
+// flowLayout arranges objects left to right, starting a new line when the available width is exceeded.
+// MinSize isn't given a width, so the width is provided by the viewer to get the wrapped height before the first layout.
+type flowLayout struct {
+	width func() float32
+}
+
+func (l *flowLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	l.arrange(objects, size.Width, true)
+}
+
+func (l *flowLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	return l.arrange(objects, l.width(), false)
+}
+
+// arrange positions the objects if move is true, and returns the size needed to fit them within the width.
+// The returned width is that of the widest object so the text can always wrap to fit narrower containers.
+func (l *flowLayout) arrange(objects []fyne.CanvasObject, width float32, move bool) fyne.Size {
+	var x, y, line, widest float32
+	for _, o := range objects {
+		if !o.Visible() {
+			continue
+		}
+		size := o.MinSize()
+		if x > 0 && width > 0 && x+size.Width > width {
+			x = 0
+			y += line
+			line = 0
+		}
+		if move {
+			o.Move(fyne.NewPos(x, y))
+			o.Resize(size)
+		}
+		x += size.Width
+		if size.Width > widest {
+			widest = size.Width
+		}
+		if size.Height > line {
+			line = size.Height
+		}
+	}
+	return fyne.NewSize(widest, y+line)
+}
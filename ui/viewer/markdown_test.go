@@ -0,0 +1,128 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestFlowLayout_MinSize(t *testing.T) {
+	test.NewApp()
+	var objects []fyne.CanvasObject
+	for i := 0; i < 5; i++ {
+		r := canvas.NewRectangle(color.Black)
+		r.SetMinSize(fyne.NewSize(40, 10))
+		objects = append(objects, r)
+	}
+	width := float32(100)
+	layout := &flowLayout{
+		width: func() float32 {
+			return width
+		},
+	}
+	// Height must match the wrapped lines even before the first layout
+	assert.Equal(t, fyne.NewSize(40, 30), layout.MinSize(objects))
+	layout.Layout(objects, fyne.NewSize(100, 30))
+	assert.Equal(t, fyne.NewPos(40, 0), objects[1].Position())
+	assert.Equal(t, fyne.NewPos(0, 10), objects[2].Position())
+	assert.Equal(t, fyne.NewPos(0, 20), objects[4].Position())
+	width = 160
+	assert.Equal(t, fyne.NewSize(40, 20), layout.MinSize(objects))
+}
+
+func TestParseMarkdown(t *testing.T) {
+	blocks := parseMarkdown("# Title\n\nSome *text*\n\n- item\n  - nested\n\n> quote\n\n```\ncode\n```\n\n---\n")
+	var kinds []markdownBlockKind
+	for _, b := range blocks {
+		kinds = append(kinds, b.kind)
+	}
+	assert.Equal(t, []markdownBlockKind{
+		markdownHeading,
+		markdownParagraph,
+		markdownListItem,
+		markdownListItem,
+		markdownQuote,
+		markdownCode,
+		markdownRule,
+	}, kinds)
+	assert.Equal(t, "Title", blocks[0].text)
+	assert.Equal(t, 1, blocks[3].level)
+	assert.Equal(t, "code", blocks[5].text)
+}
+
+func TestParseMarkdown_Quote(t *testing.T) {
+	blocks := parseMarkdown("> first\n> second\n>\n> third\n\n> another\ntext")
+	assert.Equal(t, 3, len(blocks))
+	assert.Equal(t, markdownQuote, blocks[0].kind)
+	assert.Equal(t, "first second third", blocks[0].text)
+	assert.Equal(t, markdownQuote, blocks[1].kind)
+	assert.Equal(t, "another", blocks[1].text)
+	assert.Equal(t, markdownParagraph, blocks[2].kind)
+}
+
+func TestParseMarkdownInline(t *testing.T) {
+	italic := fyne.TextStyle{Italic: true}
+	bold := fyne.TextStyle{Bold: true}
+	for name, tt := range map[string]struct {
+		given string
+		want  []*markdownSpan
+	}{
+		"Italic": {"a *b* c", []*markdownSpan{
+			{text: "a "},
+			{text: "b", style: italic},
+			{text: " c"},
+		}},
+		"Bold": {"**b**", []*markdownSpan{
+			{text: "b", style: bold},
+		}},
+		"Underscore": {"_b_", []*markdownSpan{
+			{text: "b", style: italic},
+		}},
+		"Multiplication": {"2 * 3 * 4", []*markdownSpan{
+			{text: "2 * 3 * 4"},
+		}},
+		"Unclosed": {"a*b", []*markdownSpan{
+			{text: "a*b"},
+		}},
+		"SpaceBeforeClose": {"*a *", []*markdownSpan{
+			{text: "*a *"},
+		}},
+		"WithinWord": {"a*b*c", []*markdownSpan{
+			{text: "a"},
+			{text: "b", style: italic},
+			{text: "c"},
+		}},
+		"SnakeCase": {"snake_case_name", []*markdownSpan{
+			{text: "snake_case_name"},
+		}},
+		"Escaped": {`\*a*`, []*markdownSpan{
+			{text: "*a*"},
+		}},
+		"Code": {"`a*b`", []*markdownSpan{
+			{text: "a*b", style: fyne.TextStyle{Monospace: true}},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseMarkdownInline(tt.given))
+		})
+	}
+}
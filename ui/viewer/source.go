@@ -0,0 +1,654 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const sourceTabWidth = 4
+
+type sourceTokenKind int
+
+const (
+	sourcePlain sourceTokenKind = iota
+	sourceKeyword
+	sourceString
+	sourceNumber
+	sourceComment
+)
+
+// sourceLanguage describes the lexical elements of a language needed to highlight it.
+type sourceLanguage struct {
+	lineComments  []string
+	blockComments [][2]string
+	// quotes lists the string delimiters, longest first so triple quotes take precedence
+	quotes []string
+	// multiline lists the string delimiters which may span lines
+	multiline map[string]bool
+	// literal lists the string delimiters within which a backslash doesn't escape
+	literal         map[string]bool
+	keywords        map[string]bool
+	caseInsensitive bool
+	// tags highlights the names of XML elements
+	tags bool
+}
+
+func keywords(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	sourceGo = &sourceLanguage{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, "'", "`"},
+		multiline:     keywords("`"),
+		literal:       keywords("`"),
+		keywords:      keywords("break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "false", "for", "func", "go", "goto", "if", "import", "interface", "iota", "map", "nil", "package", "range", "return", "select", "struct", "switch", "true", "type", "var"),
+	}
+	sourcePython = &sourceLanguage{
+		lineComments: []string{"#"},
+		quotes:       []string{`"""`, "'''", `"`, "'"},
+		multiline:    keywords(`"""`, "'''"),
+		keywords:     keywords("False", "None", "True", "and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield"),
+	}
+	sourceJavaScript = &sourceLanguage{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, "'", "`"},
+		multiline:     keywords("`"),
+		keywords:      keywords("async", "await", "break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "export", "extends", "false", "finally", "for", "function", "if", "import", "in", "instanceof", "let", "new", "null", "return", "super", "switch", "this", "throw", "true", "try", "typeof", "undefined", "var", "void", "while", "with", "yield"),
+	}
+	sourceShell = &sourceLanguage{
+		lineComments: []string{"#"},
+		quotes:       []string{`"`, "'"},
+		multiline:    keywords(`"`, "'"),
+		literal:      keywords("'"),
+		keywords:     keywords("case", "do", "done", "elif", "else", "esac", "export", "fi", "for", "function", "if", "in", "local", "return", "then", "until", "while"),
+	}
+	sourceJSON = &sourceLanguage{
+		quotes:   []string{`"`},
+		keywords: keywords("false", "null", "true"),
+	}
+	sourceYAML = &sourceLanguage{
+		lineComments: []string{"#"},
+		quotes:       []string{`"`, "'"},
+		literal:      keywords("'"),
+		keywords:     keywords("false", "no", "null", "true", "yes", "~"),
+	}
+	sourceTOML = &sourceLanguage{
+		lineComments: []string{"#"},
+		quotes:       []string{`"""`, "'''", `"`, "'"},
+		multiline:    keywords(`"""`, "'''"),
+		literal:      keywords("'''", "'"),
+		keywords:     keywords("false", "true"),
+	}
+	sourceXML = &sourceLanguage{
+		blockComments: [][2]string{{"<!--", "-->"}},
+		quotes:        []string{`"`, "'"},
+		literal:       keywords(`"`, "'"),
+		tags:          true,
+	}
+	sourceSQL = &sourceLanguage{
+		lineComments:    []string{"--"},
+		blockComments:   [][2]string{{"/*", "*/"}},
+		quotes:          []string{"'", `"`},
+		literal:         keywords("'", `"`),
+		caseInsensitive: true,
+		keywords:        keywords("add", "all", "alter", "and", "as", "asc", "between", "by", "case", "create", "delete", "desc", "distinct", "drop", "else", "end", "exists", "false", "from", "group", "having", "in", "index", "inner", "insert", "into", "is", "join", "key", "left", "like", "limit", "not", "null", "on", "or", "order", "outer", "primary", "references", "right", "select", "set", "table", "then", "true", "union", "update", "values", "view", "when", "where"),
+	}
+)
+
+// sourceMimeTypes maps each mime type to the language it contains.
+var sourceMimeTypes = map[string]*sourceLanguage{
+	"text/x-go":              sourceGo,
+	"text/x-python":          sourcePython,
+	"text/x-script.python":   sourcePython,
+	"application/x-python":   sourcePython,
+	"text/javascript":        sourceJavaScript,
+	"application/javascript": sourceJavaScript,
+	"application/x-sh":       sourceShell,
+	"text/x-shellscript":     sourceShell,
+	"text/x-sh":              sourceShell,
+	"application/json":       sourceJSON,
+	"application/x-yaml":     sourceYAML,
+	"application/yaml":       sourceYAML,
+	"text/yaml":              sourceYAML,
+	"text/x-yaml":            sourceYAML,
+	"application/toml":       sourceTOML,
+	"text/x-toml":            sourceTOML,
+	"application/xml":        sourceXML,
+	"text/xml":               sourceXML,
+	"application/sql":        sourceSQL,
+	"text/x-sql":             sourceSQL,
+}
+
+// sourceExtensions maps file extensions to the mime type of the language they contain.
+var sourceExtensions = map[string]string{
+	".go":   "text/x-go",
+	".py":   "text/x-python",
+	".js":   "text/javascript",
+	".mjs":  "text/javascript",
+	".sh":   "application/x-sh",
+	".bash": "application/x-sh",
+	".json": "application/json",
+	".yaml": "application/x-yaml",
+	".yml":  "application/x-yaml",
+	".toml": "application/toml",
+	".xml":  "application/xml",
+	".sql":  "application/sql",
+}
+
+func init() {
+	for m := range sourceMimeTypes {
+		mime := m
+		RegisterViewer(m, "Source", PRIORITY_DEFAULT, func() (Viewer, error) {
+			return NewSourceViewer(mime), nil
+		})
+		RegisterTextual(m)
+	}
+	for e, m := range sourceExtensions {
+		RegisterExtension(e, m)
+	}
+}
+
+// sourceToken is a run of source code of a single kind.
+type sourceToken struct {
+	kind sourceTokenKind
+	text string
+}
+
+// SourceViewer displays source code with syntax highlighting, line numbers, and search.
+type SourceViewer struct {
+	widget.BaseWidget
+	language *sourceLanguage
+	lines    [][]*sourceToken
+	// text holds the plain text of each line, for searching
+	text []string
+	// matches holds the indices of lines matching the search, and match the current one
+	matches []int
+	match   int
+	// selected is the line to select once the list has been created, or -1
+	selected int
+	// width is that of the longest line and its number, so long lines can be scrolled to rather than clipped
+	width float32
+	list  *widget.List
+	lock  sync.Mutex
+}
+
+// NewSourceViewer returns a SourceViewer highlighting the language of the given mime type.
+func NewSourceViewer(mime string) *SourceViewer {
+	language, ok := sourceMimeTypes[strings.ToLower(mime)]
+	if !ok {
+		language = &sourceLanguage{}
+	}
+	v := &SourceViewer{
+		language: language,
+		selected: -1,
+	}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *SourceViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &sourceViewerRenderer{
+		viewer: v,
+		count:  widget.NewLabel(""),
+	}
+	r.list = &widget.List{
+		Length: func() int {
+			v.lock.Lock()
+			defer v.lock.Unlock()
+			return len(v.lines)
+		},
+		CreateItem: func() fyne.CanvasObject {
+			number := canvas.NewText("", theme.DisabledTextColor())
+			number.TextStyle = fyne.TextStyle{
+				Monospace: true,
+			}
+			number.Alignment = fyne.TextAlignTrailing
+			background := canvas.NewRectangle(color.Transparent)
+			return container.NewMax(background, container.NewBorder(nil, nil, number, nil, container.New(&sourceLineLayout{})))
+		},
+		UpdateItem: r.updateItem,
+	}
+	r.list.ExtendBaseWidget(r.list)
+	v.lock.Lock()
+	v.list = r.list
+	selected := v.selected
+	v.lock.Unlock()
+	if selected >= 0 {
+		r.list.Select(selected)
+	}
+	r.search = widget.NewEntry()
+	r.search.SetPlaceHolder("Search")
+	r.search.OnChanged = v.Search
+	r.search.OnSubmitted = func(string) {
+		v.NextMatch(1)
+	}
+	bar := container.NewBorder(nil, nil, nil, container.NewHBox(
+		r.count,
+		widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+			v.NextMatch(-1)
+		}),
+		widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+			v.NextMatch(1)
+		}),
+	), r.search)
+	r.scroller = container.NewHScroll(container.New(&sourceContentLayout{viewer: v}, r.list))
+	r.content = container.NewBorder(bar, nil, nil, nil, r.scroller)
+	r.objects = []fyne.CanvasObject{r.content}
+	return r
+}
+
+func (v *SourceViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+func (v *SourceViewer) SetSource(source io.Reader) error {
+	bytes, err := ioutil.ReadAll(source)
+	if err != nil {
+		return err
+	}
+	text := strings.ReplaceAll(strings.ReplaceAll(string(bytes), "\r\n", "\n"), "\t", strings.Repeat(" ", sourceTabWidth))
+	// Don't show an empty line after the final newline
+	text = strings.TrimSuffix(text, "\n")
+	lines := splitSourceLines(tokenizeSource(text, v.language))
+	texts := strings.Split(text, "\n")
+	longest := 0
+	for _, t := range texts {
+		if l := utf8.RuneCountInString(t); l > longest {
+			longest = l
+		}
+	}
+	// Text is monospaced, so the longest line has the most characters
+	digits := len(strconv.Itoa(len(lines)))
+	char := fyne.MeasureText("0", theme.TextSize(), fyne.TextStyle{
+		Monospace: true,
+	})
+	width := float32(digits+2+longest)*char.Width + theme.Padding()*4
+	v.lock.Lock()
+	v.lines = lines
+	v.text = texts
+	v.width = width
+	v.matches = nil
+	v.lock.Unlock()
+	v.Refresh()
+	return nil
+}
+
+// Search finds the lines containing the query, ignoring case.
+func (v *SourceViewer) Search(query string) {
+	query = strings.ToLower(query)
+	v.lock.Lock()
+	v.matches = nil
+	v.match = 0
+	if query != "" {
+		for i, l := range v.text {
+			if strings.Contains(strings.ToLower(l), query) {
+				v.matches = append(v.matches, i)
+			}
+		}
+	}
+	v.lock.Unlock()
+	v.Refresh()
+	v.NextMatch(0)
+}
+
+// NextMatch moves the given number of matches forwards, or backwards if negative, and scrolls to show it.
+func (v *SourceViewer) NextMatch(delta int) {
+	v.lock.Lock()
+	count := len(v.matches)
+	if count == 0 {
+		v.lock.Unlock()
+		return
+	}
+	v.match = ((v.match+delta)%count + count) % count
+	line := v.matches[v.match]
+	list := v.list
+	v.lock.Unlock()
+	v.Refresh()
+	if list != nil {
+		list.Select(line)
+	}
+}
+
+// ShowLine selects the given line, counting from zero, and scrolls to show it.
+func (v *SourceViewer) ShowLine(line int) {
+	v.lock.Lock()
+	v.selected = line
+	list := v.list
+	v.lock.Unlock()
+	if list != nil {
+		list.Select(line)
+	}
+}
+
+type sourceViewerRenderer struct {
+	viewer   *SourceViewer
+	list     *widget.List
+	search   *widget.Entry
+	count    *widget.Label
+	scroller *container.Scroll
+	content  *fyne.Container
+	objects  []fyne.CanvasObject
+}
+
+func (r *sourceViewerRenderer) Destroy() {}
+
+func (r *sourceViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *sourceViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *sourceViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *sourceViewerRenderer) Refresh() {
+	r.viewer.lock.Lock()
+	count := len(r.viewer.matches)
+	match := r.viewer.match
+	r.viewer.lock.Unlock()
+	switch {
+	case r.search.Text == "":
+		r.count.SetText("")
+	case count == 0:
+		r.count.SetText("No matches")
+	default:
+		r.count.SetText(fmt.Sprintf("%d of %d", match+1, count))
+	}
+	r.list.Refresh()
+	r.scroller.Refresh()
+}
+
+func (r *sourceViewerRenderer) updateItem(id widget.ListItemID, item fyne.CanvasObject) {
+	v := r.viewer
+	v.lock.Lock()
+	if id < 0 || id >= len(v.lines) {
+		v.lock.Unlock()
+		return
+	}
+	tokens := v.lines[id]
+	digits := len(strconv.Itoa(len(v.lines)))
+	matched := false
+	for _, m := range v.matches {
+		if m == id {
+			matched = true
+			break
+		}
+	}
+	v.lock.Unlock()
+
+	objects := item.(*fyne.Container).Objects
+	background := objects[0].(*canvas.Rectangle)
+	if matched {
+		background.FillColor = theme.FocusColor()
+	} else {
+		background.FillColor = color.Transparent
+	}
+	background.Refresh()
+	row := objects[1].(*fyne.Container)
+	var line *fyne.Container
+	var number *canvas.Text
+	for _, o := range row.Objects {
+		switch o := o.(type) {
+		case *canvas.Text:
+			number = o
+		case *fyne.Container:
+			line = o
+		}
+	}
+	number.Text = fmt.Sprintf("%*d  ", digits, id+1)
+	number.Refresh()
+	// Reuse the texts of the row, only creating more when the line has more tokens
+	texts := line.Objects
+	for i, t := range tokens {
+		if i == len(texts) {
+			texts = append(texts, canvas.NewText("", color.Transparent))
+		}
+		text := texts[i].(*canvas.Text)
+		text.Text = t.text
+		text.Color = sourceTokenColor(t.kind)
+		text.TextStyle = fyne.TextStyle{
+			Monospace: true,
+			Italic:    t.kind == sourceComment,
+		}
+		text.Refresh()
+	}
+	line.Objects = texts[:len(tokens)]
+	line.Refresh()
+}
+
+// sourceContentLayout fills the scroller with the list, which is at least as wide as the longest line.
+type sourceContentLayout struct {
+	viewer *SourceViewer
+}
+
+func (l *sourceContentLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	for _, o := range objects {
+		o.Move(fyne.NewPos(0, 0))
+		o.Resize(size)
+	}
+}
+
+func (l *sourceContentLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	l.viewer.lock.Lock()
+	width := l.viewer.width
+	l.viewer.lock.Unlock()
+	size := fyne.NewSize(width, 0)
+	for _, o := range objects {
+		size = size.Max(o.MinSize())
+	}
+	return size
+}
+
+// sourceLineLayout places tokens of a line side by side.
+type sourceLineLayout struct{}
+
+func (l *sourceLineLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	x := float32(0)
+	for _, o := range objects {
+		m := o.MinSize()
+		o.Move(fyne.NewPos(x, (size.Height-m.Height)/2))
+		o.Resize(m)
+		x += m.Width
+	}
+}
+
+func (l *sourceLineLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	// Measure a character so empty lines have the same height as others
+	s := fyne.MeasureText("0", theme.TextSize(), fyne.TextStyle{
+		Monospace: true,
+	})
+	width := float32(0)
+	for _, o := range objects {
+		width += o.MinSize().Width
+	}
+	return fyne.NewSize(width, s.Height)
+}
+
+func sourceTokenColor(kind sourceTokenKind) color.Color {
+	switch kind {
+	case sourceKeyword:
+		return theme.PrimaryColor()
+	case sourceString:
+		return color.NRGBA{R: 0x43, G: 0xa0, B: 0x47, A: 0xff}
+	case sourceNumber:
+		return color.NRGBA{R: 0xef, G: 0x6c, B: 0x00, A: 0xff}
+	case sourceComment:
+		return theme.DisabledTextColor()
+	}
+	return theme.TextColor()
+}
+
+// tokenizeSource splits the text into tokens of the given language.
+func tokenizeSource(text string, language *sourceLanguage) []*sourceToken {
+	var tokens []*sourceToken
+	plain := strings.Builder{}
+	flush := func() {
+		if plain.Len() > 0 {
+			tokens = append(tokens, &sourceToken{sourcePlain, plain.String()})
+			plain.Reset()
+		}
+	}
+	emit := func(kind sourceTokenKind, s string) {
+		if kind == sourcePlain {
+			// Merge adjacent plain text into a single token
+			plain.WriteString(s)
+			return
+		}
+		flush()
+		tokens = append(tokens, &sourceToken{kind, s})
+	}
+	previous := rune(0)
+outer:
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		for _, c := range language.lineComments {
+			if strings.HasPrefix(rest, c) {
+				end := strings.IndexByte(rest, '\n')
+				if end < 0 {
+					end = len(rest)
+				}
+				emit(sourceComment, rest[:end])
+				i += end
+				continue outer
+			}
+		}
+		for _, c := range language.blockComments {
+			if strings.HasPrefix(rest, c[0]) {
+				end := strings.Index(rest[len(c[0]):], c[1])
+				if end < 0 {
+					end = len(rest)
+				} else {
+					end += len(c[0]) + len(c[1])
+				}
+				emit(sourceComment, rest[:end])
+				i += end
+				continue outer
+			}
+		}
+		for _, q := range language.quotes {
+			if strings.HasPrefix(rest, q) {
+				end := sourceStringEnd(rest, q, language.multiline[q], language.literal[q])
+				emit(sourceString, rest[:end])
+				i += end
+				continue outer
+			}
+		}
+		r := rune(text[i])
+		switch {
+		case isSourceWordStart(r):
+			end := 1
+			for end < len(rest) && isSourceWord(rune(rest[end])) {
+				end++
+			}
+			word := rest[:end]
+			key := word
+			if language.caseInsensitive {
+				key = strings.ToLower(word)
+			}
+			if language.keywords[key] || (language.tags && (previous == '<' || previous == '/')) {
+				emit(sourceKeyword, word)
+			} else {
+				emit(sourcePlain, word)
+			}
+			i += end
+			previous = rune(word[len(word)-1])
+			continue
+		case unicode.IsDigit(r) && !isSourceWord(previous):
+			end := 1
+			for end < len(rest) && (isSourceWord(rune(rest[end])) || rest[end] == '.') {
+				end++
+			}
+			emit(sourceNumber, rest[:end])
+			i += end
+			previous = rune(rest[end-1])
+			continue
+		}
+		emit(sourcePlain, text[i:i+1])
+		previous = r
+		i++
+	}
+	flush()
+	return tokens
+}
+
+// sourceStringEnd returns the index after the closing quote of the string at the start of the text.
+// Backslashes escape the following character unless the string is literal.
+func sourceStringEnd(text, quote string, multiline, literal bool) int {
+	for i := len(quote); i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && !literal:
+			i++
+		case strings.HasPrefix(text[i:], quote):
+			return i + len(quote)
+		case text[i] == '\n' && !multiline:
+			return i
+		}
+	}
+	return len(text)
+}
+
+func isSourceWordStart(r rune) bool {
+	return r == '_' || r == '$' || r == '~' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r >= 0x80
+}
+
+func isSourceWord(r rune) bool {
+	return isSourceWordStart(r) || (r >= '0' && r <= '9')
+}
+
+// splitSourceLines divides the tokens into lines, splitting tokens that span lines.
+func splitSourceLines(tokens []*sourceToken) [][]*sourceToken {
+	lines := [][]*sourceToken{nil}
+	for _, t := range tokens {
+		parts := strings.Split(t.text, "\n")
+		for i, p := range parts {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if p != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], &sourceToken{t.kind, p})
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSourceViewer_Width(t *testing.T) {
+	test.NewApp()
+	short := NewSourceViewer("text/x-go")
+	assert.Nil(t, short.SetSource(strings.NewReader("package main\n")))
+	long := NewSourceViewer("text/x-go")
+	assert.Nil(t, long.SetSource(strings.NewReader("package main\n\nvar s = \""+strings.Repeat("x", 500)+"\"\n")))
+	assert.Greater(t, long.width, short.width)
+
+	// Content reports the width of the longest line so it scrolls instead of being clipped
+	layout := &sourceContentLayout{viewer: long}
+	assert.Equal(t, long.width, layout.MinSize([]fyne.CanvasObject{canvas.NewRectangle(color.Black)}).Width)
+
+	w := test.NewWindow(long)
+	defer w.Close()
+	w.Resize(fyne.NewSize(200, 200))
+	assert.GreaterOrEqual(t, long.list.Size().Width, long.width)
+}
+
+func TestSourceLineLayout_MinSize(t *testing.T) {
+	test.NewApp()
+	var objects []fyne.CanvasObject
+	for i := 0; i < 3; i++ {
+		r := canvas.NewRectangle(color.Black)
+		r.SetMinSize(fyne.NewSize(10, 1))
+		objects = append(objects, r)
+	}
+	layout := &sourceLineLayout{}
+	assert.Equal(t, float32(30), layout.MinSize(objects).Width)
+	// Empty lines are as tall as others
+	assert.Equal(t, layout.MinSize(objects).Height, layout.MinSize(nil).Height)
+}
+
+func TestTokenizeSource(t *testing.T) {
+	tokens := tokenizeSource("func main() { // start\n\treturn \"hi\" + 42\n}", sourceMimeTypes["text/x-go"])
+	kinds := make(map[string]sourceTokenKind)
+	for _, t := range tokens {
+		kinds[strings.TrimSpace(t.text)] = t.kind
+	}
+	assert.Equal(t, sourceKeyword, kinds["func"])
+	assert.Equal(t, sourceKeyword, kinds["return"])
+	assert.Equal(t, sourceComment, kinds["// start"])
+	assert.Equal(t, sourceString, kinds["\"hi\""])
+	assert.Equal(t, sourceNumber, kinds["42"])
+	lines := splitSourceLines(tokens)
+	assert.Equal(t, 3, len(lines))
+}
+
+func TestTokenizeSource_Strings(t *testing.T) {
+	for name, tt := range map[string]struct {
+		mime  string
+		given string
+		want  string
+	}{
+		"GoEscaped":         {"text/x-go", `"a\"b" c`, `"a\"b"`},
+		"GoRaw":             {"text/x-go", "`a\\` c", "`a\\`"},
+		"ShellDouble":       {"application/x-sh", `"a\"b" c`, `"a\"b"`},
+		"ShellSingle":       {"application/x-sh", `'a\' c`, `'a\'`},
+		"PythonTriple":      {"text/x-python", `'''a\''' b''' c`, `'''a\''' b'''`},
+		"TOMLLiteral":       {"application/toml", `'a\' c`, `'a\'`},
+		"TOMLLiteralTriple": {"application/toml", `'''a\''' c`, `'''a\'''`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tokens := tokenizeSource(tt.given, sourceMimeTypes[tt.mime])
+			assert.Equal(t, sourceString, tokens[0].kind)
+			assert.Equal(t, tt.want, tokens[0].text)
+		})
+	}
+}
+
+func TestSourceViewer_UpdateItem(t *testing.T) {
+	test.NewApp()
+	v := NewSourceViewer("text/x-go")
+	assert.Nil(t, v.SetSource(strings.NewReader("package main\n\nfunc main() {}\n")))
+	r := test.WidgetRenderer(v).(*sourceViewerRenderer)
+	item := r.list.CreateItem()
+	var line *fyne.Container
+	for _, o := range item.(*fyne.Container).Objects[1].(*fyne.Container).Objects {
+		if c, ok := o.(*fyne.Container); ok {
+			line = c
+		}
+	}
+
+	r.updateItem(2, item)
+	assert.Equal(t, 2, len(line.Objects))
+	first := line.Objects[0].(*canvas.Text)
+	assert.Equal(t, "func", first.Text)
+	assert.Equal(t, theme.PrimaryColor(), first.Color)
+
+	// Texts are reused when the row shows another line
+	r.updateItem(0, item)
+	assert.Equal(t, 2, len(line.Objects))
+	assert.Same(t, first, line.Objects[0].(*canvas.Text))
+	assert.Equal(t, "package", first.Text)
+	assert.Equal(t, " main", line.Objects[1].(*canvas.Text).Text)
+
+	r.updateItem(1, item)
+	assert.Equal(t, 0, len(line.Objects))
+}
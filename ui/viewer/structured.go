@@ -0,0 +1,630 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	structuredRoot        = "$"
+	structuredValueLength = 100
+	// structuredMaxNodes limits the size of the tree, as YAML aliases can expand a small document exponentially
+	structuredMaxNodes = 100000
+)
+
+var errTooManyNodes = fmt.Errorf("Document has more than %d values, showing the text instead", structuredMaxNodes)
+
+// structuredParser parses data into a tree, or returns an error and the line at which it occurred, or -1 if unknown.
+type structuredParser func(data []byte) (*structuredNode, int, error)
+
+// structuredMimeTypes maps each mime type of structured data to its parser.
+var structuredMimeTypes = map[string]structuredParser{
+	"application/json":   parseJSON,
+	"application/x-yaml": parseYAML,
+	"application/yaml":   parseYAML,
+	"text/yaml":          parseYAML,
+	"text/x-yaml":        parseYAML,
+}
+
+var (
+	structuredIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	yamlErrorLine        = regexp.MustCompile(`line (\d+)`)
+)
+
+func init() {
+	for m := range structuredMimeTypes {
+		mime := m
+		// Preferred over the SourceViewer, which shows the same types as text
+		RegisterViewer(m, "Tree", PRIORITY_PREFERRED, func() (Viewer, error) {
+			return NewStructuredViewer(mime), nil
+		})
+	}
+}
+
+// structuredNode is a value within a document, identified by its path from the root.
+type structuredNode struct {
+	key      string
+	path     string
+	kind     string
+	value    string
+	children []*structuredNode
+	// yaml is the node this was parsed from, if any
+	yaml *yaml.Node
+}
+
+func (n *structuredNode) isContainer() bool {
+	return n.kind == "object" || n.kind == "array"
+}
+
+// StructuredViewer shows JSON and YAML documents as a collapsible tree, with a toggle to the raw text.
+type StructuredViewer struct {
+	widget.BaseWidget
+	parser structuredParser
+	raw    *SourceViewer
+	root   *structuredNode
+	nodes  map[string]*structuredNode
+	// err is the reason the document could not be parsed
+	err      error
+	showRaw  bool
+	selected string
+	lock     sync.Mutex
+}
+
+// NewStructuredViewer returns a StructuredViewer for the given mime type.
+func NewStructuredViewer(mime string) *StructuredViewer {
+	parser, ok := structuredMimeTypes[strings.ToLower(mime)]
+	if !ok {
+		parser = parseJSON
+	}
+	v := &StructuredViewer{
+		parser: parser,
+		raw:    NewSourceViewer(mime),
+	}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *StructuredViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &structuredViewerRenderer{
+		viewer: v,
+		path: &widget.Label{
+			Wrapping: fyne.TextTruncate,
+		},
+		message: &widget.Label{
+			Wrapping: fyne.TextWrapWord,
+		},
+	}
+	r.tree = &widget.Tree{
+		ChildUIDs: v.childUIDs,
+		IsBranch: func(uid widget.TreeNodeID) bool {
+			n := v.node(uid)
+			return uid == "" || (n != nil && n.isContainer())
+		},
+		CreateNode: func(branch bool) fyne.CanvasObject {
+			kind := canvas.NewText("", theme.DisabledTextColor())
+			kind.TextStyle = fyne.TextStyle{
+				Italic: true,
+			}
+			return container.NewHBox(
+				&widget.Label{
+					TextStyle: fyne.TextStyle{
+						Bold: true,
+					},
+				},
+				widget.NewLabel(""),
+				kind,
+			)
+		},
+		UpdateNode: func(uid widget.TreeNodeID, branch bool, node fyne.CanvasObject) {
+			n := v.node(uid)
+			if n == nil {
+				return
+			}
+			objects := node.(*fyne.Container).Objects
+			objects[0].(*widget.Label).SetText(n.key)
+			value := n.value
+			switch n.kind {
+			case "object":
+				value = fmt.Sprintf("{%d}", len(n.children))
+			case "array":
+				value = fmt.Sprintf("[%d]", len(n.children))
+			case "string":
+				value = strconv.Quote(value)
+			}
+			if len(value) > structuredValueLength {
+				value = value[:structuredValueLength] + "…"
+			}
+			objects[1].(*widget.Label).SetText(value)
+			kind := objects[2].(*canvas.Text)
+			kind.Text = n.kind
+			kind.Color = theme.DisabledTextColor()
+			kind.Refresh()
+		},
+		OnSelected: func(uid widget.TreeNodeID) {
+			v.lock.Lock()
+			v.selected = uid
+			v.lock.Unlock()
+			r.path.SetText(uid)
+		},
+	}
+	r.tree.ExtendBaseWidget(r.tree)
+	r.rawCheck = widget.NewCheck("Raw", v.SetShowRaw)
+	r.bar = container.NewBorder(nil, nil, nil, container.NewHBox(
+		widget.NewButtonWithIcon("Path", theme.ContentCopyIcon(), v.copyPath),
+		widget.NewButtonWithIcon("Value", theme.ContentCopyIcon(), v.copyValue),
+	), r.path)
+	r.rawPane = container.NewBorder(r.message, nil, nil, nil, v.raw)
+	r.content = container.NewBorder(container.NewBorder(nil, nil, nil, r.rawCheck, r.bar), nil, nil, nil, container.NewMax(r.tree, r.rawPane))
+	r.objects = []fyne.CanvasObject{r.content}
+	r.Refresh()
+	return r
+}
+
+func (v *StructuredViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+func (v *StructuredViewer) SetSource(source io.Reader) error {
+	data, err := ioutil.ReadAll(source)
+	if err != nil {
+		return err
+	}
+	root, line, err := v.parser(data)
+	text := data
+	if err == nil && json.Valid(data) {
+		// Pretty-print JSON
+		var buffer bytes.Buffer
+		if json.Indent(&buffer, data, "", "  ") == nil {
+			text = buffer.Bytes()
+		}
+	}
+	if err := v.raw.SetSource(bytes.NewReader(text)); err != nil {
+		return err
+	}
+	nodes := make(map[string]*structuredNode)
+	if root != nil {
+		var index func(*structuredNode)
+		index = func(n *structuredNode) {
+			nodes[n.path] = n
+			for _, c := range n.children {
+				index(c)
+			}
+		}
+		index(root)
+	}
+	v.lock.Lock()
+	v.root = root
+	v.nodes = nodes
+	v.err = err
+	if _, ok := nodes[v.selected]; !ok {
+		v.selected = ""
+	}
+	v.lock.Unlock()
+	if line >= 0 {
+		v.raw.ShowLine(line)
+	}
+	v.Refresh()
+	return nil
+}
+
+// SetShowRaw switches between the tree and the raw text.
+func (v *StructuredViewer) SetShowRaw(raw bool) {
+	v.lock.Lock()
+	v.showRaw = raw
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+func (v *StructuredViewer) node(uid string) *structuredNode {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.nodes[uid]
+}
+
+func (v *StructuredViewer) childUIDs(uid string) (ids []string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if uid == "" {
+		if v.root != nil {
+			ids = append(ids, v.root.path)
+		}
+		return
+	}
+	if n, ok := v.nodes[uid]; ok {
+		for _, c := range n.children {
+			ids = append(ids, c.path)
+		}
+	}
+	return
+}
+
+func (v *StructuredViewer) copyPath() {
+	v.lock.Lock()
+	path := v.selected
+	v.lock.Unlock()
+	if path != "" {
+		copyToClipboard(v, path)
+	}
+}
+
+func (v *StructuredViewer) copyValue() {
+	n := v.node(v.currentSelection())
+	if n == nil {
+		return
+	}
+	value, err := n.text()
+	if err != nil {
+		fyne.LogError("Failed to encode value", err)
+		return
+	}
+	copyToClipboard(v, value)
+}
+
+func (v *StructuredViewer) currentSelection() string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.selected
+}
+
+type structuredViewerRenderer struct {
+	viewer   *StructuredViewer
+	tree     *widget.Tree
+	path     *widget.Label
+	message  *widget.Label
+	rawCheck *widget.Check
+	bar      *fyne.Container
+	rawPane  *fyne.Container
+	content  *fyne.Container
+	objects  []fyne.CanvasObject
+}
+
+func (r *structuredViewerRenderer) Destroy() {}
+
+func (r *structuredViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *structuredViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *structuredViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *structuredViewerRenderer) Refresh() {
+	r.viewer.lock.Lock()
+	err := r.viewer.err
+	raw := r.viewer.showRaw
+	selected := r.viewer.selected
+	r.viewer.lock.Unlock()
+	if err != nil {
+		// Fallback to the text, showing why it could not be parsed
+		r.message.SetText(err.Error())
+		r.message.Show()
+		r.rawCheck.Checked = true
+		r.rawCheck.Disable()
+		raw = true
+	} else {
+		r.message.Hide()
+		r.rawCheck.Checked = raw
+		r.rawCheck.Enable()
+	}
+	if raw {
+		r.tree.Hide()
+		r.bar.Hide()
+		r.rawPane.Show()
+	} else {
+		r.rawPane.Hide()
+		r.bar.Show()
+		r.tree.Show()
+		r.path.SetText(selected)
+		r.tree.OpenBranch(structuredRoot)
+		r.tree.Refresh()
+	}
+	r.content.Refresh()
+}
+
+// text returns the value as it should be copied; the unquoted text of a scalar or the encoding of a container.
+func (n *structuredNode) text() (string, error) {
+	if !n.isContainer() {
+		return n.value, nil
+	}
+	if n.yaml != nil {
+		bytes, err := yaml.Marshal(n.yaml)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes), nil
+	}
+	var compact bytes.Buffer
+	writeJSON(&compact, n)
+	var buffer bytes.Buffer
+	if err := json.Indent(&buffer, compact.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// writeJSON writes the compact encoding of the node, preserving the order of keys.
+func writeJSON(buffer *bytes.Buffer, n *structuredNode) {
+	switch n.kind {
+	case "object":
+		buffer.WriteByte('{')
+		for i, c := range n.children {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			buffer.WriteString(strconv.Quote(c.key))
+			buffer.WriteByte(':')
+			writeJSON(buffer, c)
+		}
+		buffer.WriteByte('}')
+	case "array":
+		buffer.WriteByte('[')
+		for i, c := range n.children {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeJSON(buffer, c)
+		}
+		buffer.WriteByte(']')
+	case "string":
+		bytes, _ := json.Marshal(n.value)
+		buffer.Write(bytes)
+	default:
+		buffer.WriteString(n.value)
+	}
+}
+
+// structuredKeyPath returns the path of the value with the given key in the object at the given path.
+func structuredKeyPath(path, key string) string {
+	if structuredIdentifier.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// structuredIndexPath returns the path of the value at the given index in the array at the given path.
+func structuredIndexPath(path string, index int) string {
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+// parseJSON parses the JSON document, preserving the order of keys.
+func parseJSON(data []byte) (*structuredNode, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	root, err := parseJSONValue(decoder, structuredRoot, structuredRoot)
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			return root, -1, nil
+		} else if err == nil {
+			err = errors.New("Unexpected data after value")
+		}
+	}
+	offset := decoder.InputOffset()
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		offset = syntax.Offset
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := bytes.Count(data[:offset], []byte{'\n'})
+	return nil, line, fmt.Errorf("Could not parse JSON at line %d: %w", line+1, err)
+}
+
+func parseJSONValue(decoder *json.Decoder, key, path string) (*structuredNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	n := &structuredNode{
+		key:  key,
+		path: path,
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n.kind = "object"
+			for decoder.More() {
+				token, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := token.(string)
+				if !ok {
+					return nil, fmt.Errorf("Expected key, got %v", token)
+				}
+				c, err := parseJSONValue(decoder, k, structuredKeyPath(path, k))
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, c)
+			}
+		case '[':
+			n.kind = "array"
+			for i := 0; decoder.More(); i++ {
+				c, err := parseJSONValue(decoder, fmt.Sprintf("[%d]", i), structuredIndexPath(path, i))
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, c)
+			}
+		default:
+			return nil, fmt.Errorf("Unexpected %v", t)
+		}
+		// Consume closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind = "string"
+		n.value = t
+	case json.Number:
+		n.kind = "number"
+		n.value = t.String()
+	case bool:
+		n.kind = "boolean"
+		n.value = strconv.FormatBool(t)
+	case nil:
+		n.kind = "null"
+		n.value = "null"
+	}
+	return n, nil
+}
+
+// parseYAML parses the first document of the YAML stream.
+func parseYAML(data []byte) (*structuredNode, int, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		line := -1
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			if l, err := strconv.Atoi(m[1]); err == nil {
+				line = l - 1
+			}
+		}
+		return nil, line, fmt.Errorf("Could not parse YAML: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return &structuredNode{
+			key:   structuredRoot,
+			path:  structuredRoot,
+			kind:  "null",
+			value: "null",
+		}, -1, nil
+	}
+	c := &yamlConverter{
+		expanding: make(map[*yaml.Node]bool),
+	}
+	root, err := c.convert(document.Content[0], structuredRoot, structuredRoot)
+	if err != nil {
+		return nil, -1, err
+	}
+	return root, -1, nil
+}
+
+// yamlConverter converts YAML nodes into a tree, expanding aliases while guarding against cycles and exponential growth.
+type yamlConverter struct {
+	// expanding holds the containers currently being converted, an alias to any of them is a cycle
+	expanding map[*yaml.Node]bool
+	count     int
+}
+
+func (c *yamlConverter) convert(node *yaml.Node, key, path string) (*structuredNode, error) {
+	c.count++
+	if c.count > structuredMaxNodes {
+		return nil, errTooManyNodes
+	}
+	alias := node
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if c.expanding[node] {
+		// Show a cyclic alias as a leaf rather than expanding it forever
+		return &structuredNode{
+			key:   key,
+			path:  path,
+			kind:  "alias",
+			value: "*" + alias.Value,
+			yaml:  alias,
+		}, nil
+	}
+	n := &structuredNode{
+		key:  key,
+		path: path,
+		yaml: node,
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		n.kind = "object"
+		c.expanding[node] = true
+		defer delete(c.expanding, node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := node.Content[i].Value
+			child, err := c.convert(node.Content[i+1], k, structuredKeyPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+	case yaml.SequenceNode:
+		n.kind = "array"
+		c.expanding[node] = true
+		defer delete(c.expanding, node)
+		for i, e := range node.Content {
+			child, err := c.convert(e, fmt.Sprintf("[%d]", i), structuredIndexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+	default:
+		n.value = node.Value
+		switch node.ShortTag() {
+		case "!!str":
+			n.kind = "string"
+		case "!!int", "!!float":
+			n.kind = "number"
+		case "!!bool":
+			n.kind = "boolean"
+		case "!!null":
+			n.kind = "null"
+			n.value = "null"
+		default:
+			n.kind = strings.TrimPrefix(node.ShortTag(), "!!")
+		}
+	}
+	return n, nil
+}
+
+// copyToClipboard copies the text to the clipboard of the window showing the object.
+func copyToClipboard(object fyne.CanvasObject, text string) {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	c := app.Driver().CanvasForObject(object)
+	for _, w := range app.Driver().AllWindows() {
+		if w.Canvas() == c {
+			w.Clipboard().SetContent(text)
+			return
+		}
+	}
+}
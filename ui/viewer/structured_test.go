@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJSON(t *testing.T) {
+	t.Run("Order", func(t *testing.T) {
+		root, line, err := parseJSON([]byte(`{"b": 1, "a": [true, null, "x"]}`))
+		assert.Nil(t, err)
+		assert.Equal(t, -1, line)
+		assert.Equal(t, "b", root.children[0].key)
+		assert.Equal(t, "a", root.children[1].key)
+		assert.Equal(t, "$.a[2]", root.children[1].children[2].path)
+		assert.Equal(t, "null", root.children[1].children[1].kind)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, line, err := parseJSON([]byte("{\n\"a\": 1,\n\"b\": }"))
+		assert.NotNil(t, err)
+		assert.Equal(t, 2, line)
+	})
+}
+
+func TestParseYAML(t *testing.T) {
+	t.Run("Alias", func(t *testing.T) {
+		root, _, err := parseYAML([]byte("base: &b\n  x: 1\ncopy: *b\n"))
+		assert.Nil(t, err)
+		assert.Equal(t, "object", root.children[1].kind)
+		assert.Equal(t, "$.copy.x", root.children[1].children[0].path)
+	})
+	t.Run("CyclicAlias", func(t *testing.T) {
+		done := make(chan struct{})
+		var (
+			root *structuredNode
+			err  error
+		)
+		go func() {
+			root, _, err = parseYAML([]byte("a: &x [1, *x]\n"))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Cyclic alias was expanded forever")
+		}
+		assert.Nil(t, err)
+		a := root.children[0]
+		assert.Equal(t, 2, len(a.children))
+		assert.Equal(t, "alias", a.children[1].kind)
+		assert.Equal(t, "*x", a.children[1].value)
+		text, err := a.children[1].text()
+		assert.Nil(t, err)
+		assert.Equal(t, "*x", strings.TrimSpace(text))
+	})
+	t.Run("BillionLaughs", func(t *testing.T) {
+		document := "a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n"
+		for i, c := range "bcdefghi" {
+			previous := string(rune('a' + i))
+			document += string(c) + ": &" + string(c) + " [" + strings.Repeat("*"+previous+", ", 8) + "*" + previous + "]\n"
+		}
+		_, _, err := parseYAML([]byte(document))
+		assert.Equal(t, errTooManyNodes, err)
+	})
+}
+
+func TestStructuredViewer_TooManyNodes(t *testing.T) {
+	test.NewApp()
+	v := NewStructuredViewer("application/x-yaml")
+	document := "a: &a [" + strings.Repeat("lol, ", 999) + "lol]\nb: &b [" + strings.Repeat("*a, ", 199) + "*a]\n"
+	assert.Nil(t, v.SetSource(strings.NewReader(document)))
+	// Falls back to the raw text
+	assert.Equal(t, errTooManyNodes, v.err)
+	assert.Nil(t, v.root)
+}
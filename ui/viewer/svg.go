@@ -0,0 +1,215 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"aletheiaware.com/spacego"
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"image"
+	"io"
+	"math"
+	"sync"
+)
+
+const (
+	svgZoomFactor = 1.25
+	svgZoomMax    = 32
+	svgZoomMin    = 1.0 / 32
+	// svgMaxPixels limits the size of the rasterized image, as each pixel takes 4 bytes
+	svgMaxPixels = 4096 * 4096
+)
+
+func init() {
+	RegisterViewer(spacego.MIME_TYPE_IMAGE_SVG, "SVG", PRIORITY_DEFAULT, func() (Viewer, error) {
+		return NewSVGViewer(), nil
+	})
+}
+
+// SVGViewer rasterizes vector images at the pixel size of the canvas, so they remain sharp when resized or zoomed.
+type SVGViewer struct {
+	widget.BaseWidget
+	icon *oksvg.SvgIcon
+	// zoom is the scale relative to the image's view box, or 0 to fit the available space
+	zoom float32
+	lock sync.Mutex
+}
+
+func NewSVGViewer() *SVGViewer {
+	v := &SVGViewer{}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *SVGViewer) CreateRenderer() fyne.WidgetRenderer {
+	v.ExtendBaseWidget(v)
+	r := &svgViewerRenderer{
+		viewer: v,
+	}
+	r.raster = canvas.NewRaster(r.draw)
+	r.scroller = container.NewScroll(r.raster)
+	toolbar := widget.NewToolbar(
+		widget.NewToolbarAction(theme.ZoomFitIcon(), func() {
+			v.SetZoom(0)
+		}),
+		widget.NewToolbarAction(theme.ZoomOutIcon(), func() {
+			v.SetZoom(v.currentZoom() / svgZoomFactor)
+		}),
+		widget.NewToolbarAction(theme.ZoomInIcon(), func() {
+			v.SetZoom(v.currentZoom() * svgZoomFactor)
+		}),
+	)
+	r.content = container.NewBorder(toolbar, nil, nil, nil, r.scroller)
+	r.objects = []fyne.CanvasObject{r.content}
+	return r
+}
+
+func (v *SVGViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return v.BaseWidget.MinSize()
+}
+
+func (v *SVGViewer) SetSource(source io.Reader) error {
+	icon, err := oksvg.ReadIconStream(source, oksvg.WarnErrorMode)
+	if err != nil {
+		return err
+	}
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		return errors.New("SVG has no size")
+	}
+	v.lock.Lock()
+	v.icon = icon
+	if max := v.maxZoom(); float64(v.zoom) > max {
+		v.zoom = float32(max)
+	}
+	v.lock.Unlock()
+	v.Refresh()
+	return nil
+}
+
+// SetZoom sets the scale relative to the image's view box, or 0 to fit the available space.
+func (v *SVGViewer) SetZoom(zoom float32) {
+	v.lock.Lock()
+	if zoom != 0 {
+		zoom = float32(math.Min(v.maxZoom(), math.Max(svgZoomMin, float64(zoom))))
+	}
+	v.zoom = zoom
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+// maxZoom returns the largest zoom at which the image fits within the pixel budget, the caller must hold the lock.
+func (v *SVGViewer) maxZoom() float64 {
+	max := float64(svgZoomMax)
+	if v.icon != nil {
+		if z := math.Sqrt(svgMaxPixels / (v.icon.ViewBox.W * v.icon.ViewBox.H)); z < max {
+			max = z
+		}
+	}
+	return max
+}
+
+// currentZoom returns the zoom, calculating the effective zoom when fitting the available space.
+func (v *SVGViewer) currentZoom() float32 {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.zoom != 0 || v.icon == nil {
+		return v.zoom
+	}
+	size := v.Size()
+	return float32(math.Min(float64(size.Width)/v.icon.ViewBox.W, float64(size.Height)/v.icon.ViewBox.H))
+}
+
+type svgViewerRenderer struct {
+	viewer   *SVGViewer
+	raster   *canvas.Raster
+	scroller *container.Scroll
+	content  *fyne.Container
+	objects  []fyne.CanvasObject
+}
+
+func (r *svgViewerRenderer) Destroy() {}
+
+func (r *svgViewerRenderer) Layout(size fyne.Size) {
+	r.content.Resize(size)
+}
+
+func (r *svgViewerRenderer) MinSize() fyne.Size {
+	return r.content.MinSize()
+}
+
+func (r *svgViewerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *svgViewerRenderer) Refresh() {
+	r.viewer.lock.Lock()
+	icon := r.viewer.icon
+	zoom := r.viewer.zoom
+	r.viewer.lock.Unlock()
+	if icon == nil || zoom == 0 {
+		// Fill the scroller's viewport
+		r.raster.SetMinSize(fyne.NewSize(1, 1))
+	} else {
+		r.raster.SetMinSize(fyne.NewSize(float32(icon.ViewBox.W)*zoom, float32(icon.ViewBox.H)*zoom))
+	}
+	r.scroller.Refresh()
+	r.raster.Refresh()
+}
+
+// draw renders the image centered within the given pixel dimensions, preserving its aspect ratio.
+func (r *svgViewerRenderer) draw(w, h int) image.Image {
+	// Limit the resolution on high density displays, the raster is stretched to fill the canvas
+	if pixels := float64(w) * float64(h); pixels > svgMaxPixels {
+		scale := math.Sqrt(svgMaxPixels / pixels)
+		w = int(float64(w) * scale)
+		h = int(float64(h) * scale)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	r.viewer.lock.Lock()
+	defer r.viewer.lock.Unlock()
+	icon := r.viewer.icon
+	if icon == nil || w <= 0 || h <= 0 {
+		return img
+	}
+	scale := math.Min(float64(w)/icon.ViewBox.W, float64(h)/icon.ViewBox.H)
+	width := icon.ViewBox.W * scale
+	height := icon.ViewBox.H * scale
+	icon.SetTarget((float64(w)-width)/2, (float64(h)-height)/2, width, height)
+	scanner := rasterx.NewScannerGV(w, h, img, img.Bounds())
+	if err := drawSVG(icon, rasterx.NewDasher(w, h, scanner)); err != nil {
+		fyne.LogError("Failed to render SVG", err)
+	}
+	return img
+}
+
+// drawSVG recovers from any panic in the rasterizer caused by malformed images.
+func drawSVG(icon *oksvg.SvgIcon, dasher *rasterx.Dasher) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("Could not render SVG")
+		}
+	}()
+	icon.Draw(dasher, 1)
+	return nil
+}
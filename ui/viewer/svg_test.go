@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSVGViewer_Zoom(t *testing.T) {
+	test.NewApp()
+	t.Run("Small", func(t *testing.T) {
+		v := NewSVGViewer()
+		assert.Nil(t, v.SetSource(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16"><rect width="16" height="16"/></svg>`)))
+		v.SetZoom(1000)
+		assert.Equal(t, float32(svgZoomMax), v.zoom)
+	})
+	t.Run("Large", func(t *testing.T) {
+		v := NewSVGViewer()
+		assert.Nil(t, v.SetSource(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100000 100000"><rect width="100000" height="100000"/></svg>`)))
+		v.SetZoom(svgZoomMax)
+		size := float64(v.zoom) * 100000
+		assert.LessOrEqual(t, size*size, float64(svgMaxPixels)+1)
+	})
+}
+
+func TestSVGViewer_Draw(t *testing.T) {
+	test.NewApp()
+	v := NewSVGViewer()
+	assert.Nil(t, v.SetSource(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16"><rect width="16" height="16"/></svg>`)))
+	r := &svgViewerRenderer{
+		viewer: v,
+	}
+	bounds := r.draw(100000, 50000).Bounds()
+	assert.LessOrEqual(t, bounds.Dx()*bounds.Dy(), svgMaxPixels)
+	// Aspect ratio is preserved
+	assert.InDelta(t, 2, float64(bounds.Dx())/float64(bounds.Dy()), 0.01)
+}
@@ -23,17 +23,31 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"io"
 	"io/ioutil"
+	"strings"
+	"sync"
 )
 
 func init() {
-	Register(spacego.MIME_TYPE_TEXT_PLAIN, func() (Viewer, error) {
+	generator := func() (Viewer, error) {
 		return NewTextPlainViewer(), nil
-	})
+	}
+	RegisterViewer(spacego.MIME_TYPE_TEXT_PLAIN, "Text", PRIORITY_DEFAULT, generator)
+	RegisterViewer("text/*", "Text", PRIORITY_DEFAULT, generator)
 }
 
 type TextPlainViewer struct {
 	widget.BaseWidget
+	// text is the content being viewed or edited
 	text string
+	// base is the content as last read or saved
+	base string
+	// remote is the content changed elsewhere which conflicts with the unsaved changes, it replaces base once the conflict is resolved
+	remote           string
+	conflicted       bool
+	editing          bool
+	unsaved          bool
+	onUnsavedChanged func(bool)
+	lock             sync.Mutex
 }
 
 func NewTextPlainViewer() *TextPlainViewer {
@@ -49,9 +63,12 @@ func (v *TextPlainViewer) CreateRenderer() fyne.WidgetRenderer {
 		label: &widget.Label{
 			Wrapping: fyne.TextWrapWord,
 		},
+		entry: widget.NewMultiLineEntry(),
 	}
+	r.entry.Wrapping = fyne.TextWrapWord
+	r.entry.OnChanged = v.edited
 	r.scroller = container.NewVScroll(r.label)
-	r.objects = []fyne.CanvasObject{r.scroller}
+	r.objects = []fyne.CanvasObject{r.scroller, r.entry}
 	return r
 }
 
@@ -65,14 +82,123 @@ func (v *TextPlainViewer) SetSource(source io.Reader) error {
 	if err != nil {
 		return err
 	}
-	v.text = string(bytes)
+	remote := string(bytes)
+	v.lock.Lock()
+	v.conflicted = false
+	switch {
+	case !v.unsaved:
+		v.text = remote
+	case remote == v.base:
+		// No changes made elsewhere
+	default:
+		// Keep unsaved changes, merging in those made elsewhere if they don't overlap
+		if merged, ok := mergeText(v.base, v.text, remote); ok {
+			v.text = merged
+		} else {
+			// Keep base until the user chooses how to resolve the conflict, otherwise saving would silently overwrite the remote changes
+			v.remote = remote
+			v.conflicted = true
+			err = ErrConflict
+		}
+	}
+	if !v.conflicted {
+		v.base = remote
+	}
+	v.lock.Unlock()
+	v.updateUnsaved()
+	v.Refresh()
+	return err
+}
+
+func (v *TextPlainViewer) SetEditing(editing bool) {
+	v.lock.Lock()
+	v.editing = editing
+	v.lock.Unlock()
+	v.Refresh()
+}
+
+func (v *TextPlainViewer) SetOnUnsavedChanged(callback func(bool)) {
+	v.lock.Lock()
+	v.onUnsavedChanged = callback
+	v.lock.Unlock()
+}
+
+func (v *TextPlainViewer) Unsaved() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.unsaved
+}
+
+func (v *TextPlainViewer) Conflicted() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.conflicted
+}
+
+func (v *TextPlainViewer) ResolveConflict(overwrite bool) {
+	v.lock.Lock()
+	if !v.conflicted {
+		v.lock.Unlock()
+		return
+	}
+	if !overwrite {
+		v.text = conflictText(v.base, v.text, v.remote)
+	}
+	v.base = v.remote
+	v.remote = ""
+	v.conflicted = false
+	v.lock.Unlock()
+	v.updateUnsaved()
 	v.Refresh()
+}
+
+func (v *TextPlainViewer) Save(writer io.WriteCloser) error {
+	v.lock.Lock()
+	text := v.text
+	conflicted := v.conflicted
+	v.lock.Unlock()
+	if conflicted {
+		// Writing, or even closing, the writer would overwrite changes made elsewhere which the user hasn't seen
+		return ErrConflict
+	}
+	if _, err := io.WriteString(writer, text); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	v.lock.Lock()
+	v.base = text
+	v.lock.Unlock()
+	v.updateUnsaved()
 	return nil
 }
 
+func (v *TextPlainViewer) edited(text string) {
+	v.lock.Lock()
+	v.text = text
+	v.lock.Unlock()
+	v.updateUnsaved()
+}
+
+// updateUnsaved triggers the callback if the unsaved state has changed.
+func (v *TextPlainViewer) updateUnsaved() {
+	v.lock.Lock()
+	unsaved := v.text != v.base
+	changed := unsaved != v.unsaved
+	v.unsaved = unsaved
+	callback := v.onUnsavedChanged
+	v.lock.Unlock()
+	if changed && callback != nil {
+		callback(unsaved)
+	}
+}
+
 type textPlainViewerRenderer struct {
 	viewer   *TextPlainViewer
 	label    *widget.Label
+	entry    *widget.Entry
 	scroller *container.Scroll
 	objects  []fyne.CanvasObject
 }
@@ -81,6 +207,7 @@ func (r *textPlainViewerRenderer) Destroy() {}
 
 func (r *textPlainViewerRenderer) Layout(size fyne.Size) {
 	r.scroller.Resize(size)
+	r.entry.Resize(size)
 }
 
 func (r *textPlainViewerRenderer) MinSize() fyne.Size {
@@ -92,7 +219,88 @@ func (r *textPlainViewerRenderer) Objects() []fyne.CanvasObject {
 }
 
 func (r *textPlainViewerRenderer) Refresh() {
-	r.label.Text = r.viewer.text
-	r.label.Refresh()
-	r.scroller.Refresh()
+	r.viewer.lock.Lock()
+	text := r.viewer.text
+	editing := r.viewer.editing
+	r.viewer.lock.Unlock()
+	if editing {
+		r.scroller.Hide()
+		if r.entry.Text != text {
+			r.entry.SetText(text)
+		}
+		r.entry.Show()
+	} else {
+		r.entry.Hide()
+		r.label.Text = text
+		r.label.Refresh()
+		r.scroller.Show()
+		r.scroller.Refresh()
+	}
+}
+
+// mergeText combines the local and remote changes to base, returning false if the changes overlap.
+func mergeText(base, local, remote string) (string, bool) {
+	if local == base {
+		return remote, true
+	}
+	if remote == base || remote == local {
+		return local, true
+	}
+	ls, le, lt := changedRegion(base, local)
+	rs, re, rt := changedRegion(base, remote)
+	switch {
+	case le < rs:
+		return base[:ls] + lt + base[le:rs] + rt + base[re:], true
+	case re < ls:
+		return base[:rs] + rt + base[re:ls] + lt + base[le:], true
+	}
+	return "", false
+}
+
+// conflictText marks the overlapping local and remote changes to base so they can be merged by hand.
+func conflictText(base, local, remote string) string {
+	ls, le, _ := changedRegion(base, local)
+	rs, re, _ := changedRegion(base, remote)
+	start, end := ls, le
+	if rs < start {
+		start = rs
+	}
+	if re > end {
+		end = re
+	}
+	// Expand to whole lines so the markers are on lines of their own
+	start = strings.LastIndex(base[:start], "\n") + 1
+	if end == start || base[end-1] != '\n' {
+		if i := strings.Index(base[end:], "\n"); i >= 0 {
+			end += i + 1
+		} else {
+			end = len(base)
+		}
+	}
+	suffix := len(base) - end
+	return base[:start] +
+		"<<<<<<< Local\n" + withNewline(local[start:len(local)-suffix]) +
+		"=======\n" + withNewline(remote[start:len(remote)-suffix]) +
+		">>>>>>> Remote\n" + base[end:]
+}
+
+// withNewline returns the text ending with a newline, unless it is empty.
+func withNewline(text string) string {
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return text
+	}
+	return text + "\n"
+}
+
+// changedRegion returns the start and end of the region of base which was replaced, and the replacement, to produce text.
+func changedRegion(base, text string) (int, int, string) {
+	prefix := 0
+	for prefix < len(base) && prefix < len(text) && base[prefix] == text[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(text)-prefix && base[len(base)-1-suffix] == text[len(text)-1-suffix] {
+		suffix++
+	}
+	return prefix, len(base) - suffix, text[prefix : len(text)-suffix]
 }
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// nopWriteCloser records whether the writer was closed.
+type nopWriteCloser struct {
+	strings.Builder
+	closed bool
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestTextPlainViewer_Merge(t *testing.T) {
+	test.NewApp()
+	v := NewTextPlainViewer()
+	assert.Nil(t, v.SetSource(strings.NewReader("one\ntwo\nthree\n")))
+	v.edited("ONE\ntwo\nthree\n")
+	assert.Nil(t, v.SetSource(strings.NewReader("one\ntwo\nTHREE\n")))
+	assert.False(t, v.Conflicted())
+	w := &nopWriteCloser{}
+	assert.Nil(t, v.Save(w))
+	assert.Equal(t, "ONE\ntwo\nTHREE\n", w.String())
+	assert.False(t, v.Unsaved())
+}
+
+func TestTextPlainViewer_Conflict(t *testing.T) {
+	test.NewApp()
+	newConflict := func(t *testing.T) *TextPlainViewer {
+		v := NewTextPlainViewer()
+		assert.Nil(t, v.SetSource(strings.NewReader("one\ntwo\nthree\n")))
+		v.edited("one\nlocal\nthree\n")
+		assert.Equal(t, ErrConflict, v.SetSource(strings.NewReader("one\nremote\nthree\n")))
+		assert.True(t, v.Conflicted())
+		return v
+	}
+	t.Run("Save", func(t *testing.T) {
+		v := newConflict(t)
+		w := &nopWriteCloser{}
+		assert.Equal(t, ErrConflict, v.Save(w))
+		assert.Equal(t, "", w.String())
+		assert.False(t, w.closed)
+		assert.True(t, v.Unsaved())
+		// Conflict remains until resolved, even if the file is read again
+		assert.Equal(t, ErrConflict, v.SetSource(strings.NewReader("one\nremote\nthree\n")))
+		assert.Equal(t, ErrConflict, v.Save(w))
+	})
+	t.Run("Overwrite", func(t *testing.T) {
+		v := newConflict(t)
+		v.ResolveConflict(true)
+		assert.False(t, v.Conflicted())
+		w := &nopWriteCloser{}
+		assert.Nil(t, v.Save(w))
+		assert.Equal(t, "one\nlocal\nthree\n", w.String())
+		assert.True(t, w.closed)
+	})
+	t.Run("Merge", func(t *testing.T) {
+		v := newConflict(t)
+		v.ResolveConflict(false)
+		assert.False(t, v.Conflicted())
+		assert.True(t, v.Unsaved())
+		w := &nopWriteCloser{}
+		assert.Nil(t, v.Save(w))
+		assert.Equal(t, "one\n<<<<<<< Local\nlocal\n=======\nremote\n>>>>>>> Remote\nthree\n", w.String())
+	})
+}
@@ -17,14 +17,44 @@
 package viewer
 
 import (
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
 	"strings"
 )
 
-// generatorTable stores the mapping of mime types to generators of Viewers.
-var generatorTable map[string]func() (Viewer, error) = map[string]func() (Viewer, error){}
+const (
+	// PRIORITY_FALLBACK is used by viewers which can show any file, but only as a last resort.
+	PRIORITY_FALLBACK = -10
+	PRIORITY_DEFAULT  = 0
+	// PRIORITY_PREFERRED is used by viewers which should be chosen over others registered for the same type.
+	PRIORITY_PREFERRED = 10
+)
+
+// registration is a named generator of Viewers for a mime type or wildcard.
+type registration struct {
+	name      string
+	priority  int
+	order     int
+	generator func() (Viewer, error)
+}
+
+// generatorTable stores the mapping of mime types, or wildcards such as "image/*", to generators of Viewers.
+var generatorTable map[string][]*registration = map[string][]*registration{}
+
+// registrations counts registrations so that ties are broken by the order viewers were registered.
+var registrations int
+
+// extensionTable stores the mapping of file extensions to mime types.
+var extensionTable map[string]string = map[string]string{}
+
+// textualTable stores the mime types, other than "text/*", whose files contain text.
+var textualTable map[string]bool = map[string]bool{}
 
 // Viewer represents a fyne.CanvasObject that can view a file.
 type Viewer interface {
@@ -32,19 +62,263 @@ type Viewer interface {
 	SetSource(io.Reader) error
 }
 
-// Register registers a function that can generate a generator.
+// ErrConflict is returned by an Editor's SetSource when changes made elsewhere could not be merged with unsaved changes.
+var ErrConflict = errors.New("File was changed elsewhere and could not be merged with unsaved changes")
+
+// Editor represents a Viewer that can also modify the file.
+type Editor interface {
+	Viewer
+	// SetEditing switches between viewing and editing the file.
+	SetEditing(bool)
+	// SetOnUnsavedChanged sets a function that runs when the Editor gains or loses unsaved changes.
+	SetOnUnsavedChanged(func(bool))
+	// Unsaved returns true if the Editor has changes which have not been saved.
+	Unsaved() bool
+	// Save writes the changes to, and then closes, the given writer.
+	// If changes made elsewhere conflict, the writer is left untouched and ErrConflict is returned.
+	Save(io.WriteCloser) error
+	// Conflicted returns true if changes made elsewhere could not be merged, and must be resolved before saving.
+	Conflicted() bool
+	// ResolveConflict either keeps the unsaved changes so saving overwrites those made elsewhere,
+	// or marks both sets of changes in the content so they can be merged by hand.
+	ResolveConflict(overwrite bool)
+}
+
+// Player represents a Viewer that plays media over time.
+type Player interface {
+	Viewer
+	Play()
+	Pause()
+	// Stop ends playback, such as when the Viewer is closed.
+	Stop()
+}
+
+// Reopener represents a source that can be read again from the start, allowing Viewers to discard data already read.
+type Reopener interface {
+	Reopen() (io.Reader, error)
+}
+
+// NewReopenableSource returns a source that reads from the given reader, and from a new reader returned by open when reopened.
+// Readers supporting random access are returned unchanged.
+func NewReopenableSource(reader io.Reader, open func() (io.Reader, error)) io.Reader {
+	if _, ok := reader.(io.ReaderAt); ok {
+		return reader
+	}
+	return &reopenableSource{
+		Reader: reader,
+		open:   open,
+	}
+}
+
+type reopenableSource struct {
+	io.Reader
+	open func() (io.Reader, error)
+}
+
+func (s *reopenableSource) Reopen() (io.Reader, error) {
+	return s.open()
+}
+
+func (s *reopenableSource) Close() error {
+	if c, ok := s.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// DEFAULT_VIEWER_NAME names the Viewers registered by Register.
+const DEFAULT_VIEWER_NAME = "Default"
+
+// Register registers a function that can generate a Viewer for the given
+// mime, replacing any previously registered by Register for the same mime.
 func Register(mime string, generator func() (Viewer, error)) {
-	generatorTable[strings.ToLower(mime)] = generator
+	RegisterViewer(mime, DEFAULT_VIEWER_NAME, PRIORITY_DEFAULT, generator)
+}
+
+// RegisterViewer registers a named function that can generate a Viewer for
+// the given mime, which may be a wildcard such as "text/*" or "*/*".
+// When several Viewers match a mime, those with a higher priority are
+// preferred, followed by exact matches over wildcards. Registering the same
+// name for the same mime replaces the previous registration.
+func RegisterViewer(mime, name string, priority int, generator func() (Viewer, error)) {
+	mime = normalizeMime(mime)
+	registrations++
+	r := &registration{
+		name:      name,
+		priority:  priority,
+		order:     registrations,
+		generator: generator,
+	}
+	rs := generatorTable[mime]
+	for i, o := range rs {
+		if o.name == name {
+			rs[i] = r
+			return
+		}
+	}
+	generatorTable[mime] = append(rs, r)
 }
 
 // ForMime returns the Viewer instance which is registered to handle URIs
 // of the given mime.
 func ForMime(mime string) (Viewer, error) {
-	generator, ok := generatorTable[strings.ToLower(mime)]
+	candidates := candidatesFor(mime)
 
-	if !ok {
+	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no generator registered for mime '%s'", mime)
 	}
 
-	return generator()
+	return candidates[0].generator()
+}
+
+// ForViewer returns an instance of the named Viewer registered to handle the given mime.
+func ForViewer(mime, name string) (Viewer, error) {
+	for _, c := range candidatesFor(mime) {
+		if c.name == name {
+			return c.generator()
+		}
+	}
+	return nil, fmt.Errorf("no viewer '%s' registered for mime '%s'", name, mime)
+}
+
+// ViewersFor returns the names of the Viewers which can handle the given
+// mime, in order of preference.
+func ViewersFor(mime string) []string {
+	var names []string
+	for _, c := range candidatesFor(mime) {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// candidatesFor returns the registrations matching the mime, exactly or by wildcard, in order of preference.
+func candidatesFor(mime string) []*registration {
+	mime = normalizeMime(mime)
+	type candidate struct {
+		*registration
+		specificity int
+	}
+	var candidates []*candidate
+	seen := make(map[string]bool)
+	add := func(pattern string, specificity int) {
+		for _, r := range generatorTable[pattern] {
+			candidates = append(candidates, &candidate{r, specificity})
+		}
+	}
+	add(mime, 2)
+	if i := strings.Index(mime, "/"); i > 0 {
+		add(mime[:i]+"/*", 1)
+	}
+	add("*/*", 0)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		if a.specificity != b.specificity {
+			return a.specificity > b.specificity
+		}
+		return a.order < b.order
+	})
+	var results []*registration
+	for _, c := range candidates {
+		// A name matched by several patterns is only listed once, at its most preferred position
+		if seen[c.name] {
+			continue
+		}
+		seen[c.name] = true
+		results = append(results, c.registration)
+	}
+	return results
+}
+
+// normalizeMime lowercases the mime and removes any parameters, such as "; charset=utf-8".
+func normalizeMime(mime string) string {
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+// DetectMime returns the mime type of a file from its declared type, name,
+// and the first bytes of its content. The declared type is used unless it is
+// missing or contradicted by the content, in which case the type implied by
+// the name is tried before the type sniffed from the content.
+func DetectMime(name, declared string, data []byte) string {
+	declared = normalizeMime(declared)
+	sniffed := ""
+	if len(data) > 0 {
+		sniffed = normalizeMime(http.DetectContentType(data))
+	}
+	if declared != "" && declared != MIME_TYPE_APPLICATION_OCTET_STREAM && !contradicts(declared, sniffed) {
+		return declared
+	}
+	extension := MimeForName(name)
+	if extension == "" {
+		extension = normalizeMime(mime.TypeByExtension(path.Ext(name)))
+	}
+	if extension != "" && !contradicts(extension, sniffed) {
+		return extension
+	}
+	if sniffed != "" {
+		return sniffed
+	}
+	if declared != "" {
+		return declared
+	}
+	return MIME_TYPE_APPLICATION_OCTET_STREAM
+}
+
+// contradicts returns true if content sniffed as one type cannot be of the given type.
+func contradicts(mime, sniffed string) bool {
+	switch {
+	case sniffed == "":
+		return false
+	case sniffed == MIME_TYPE_APPLICATION_OCTET_STREAM:
+		// Content is binary, but not a format that can be recognized
+		return isTextual(mime)
+	case strings.HasPrefix(sniffed, "text/"):
+		return !isTextual(mime)
+	}
+	// Content is a recognized binary format, such as an image or archive, which contradicts the type unless both are shown by the same viewer
+	a, b := candidatesFor(mime), candidatesFor(sniffed)
+	return len(a) == 0 || len(b) == 0 || a[0].name != b[0].name
+}
+
+// isTextual returns true if files of the given type contain text.
+func isTextual(mime string) bool {
+	if strings.HasPrefix(mime, "text/") || strings.HasSuffix(mime, "+xml") || strings.HasSuffix(mime, "+json") {
+		return true
+	}
+	return textualTable[mime]
+}
+
+// RegisterTextual registers a mime type whose files contain text, such as
+// "application/json", so content sniffed as text doesn't contradict it.
+func RegisterTextual(mime string) {
+	textualTable[normalizeMime(mime)] = true
+}
+
+// RegisterExtension registers the mime type of files with the given extension, such as ".go".
+func RegisterExtension(extension, mime string) {
+	extensionTable[strings.ToLower(extension)] = strings.ToLower(mime)
+}
+
+// MimeForName returns the mime type registered for the extension of the
+// given file name, or an empty string if there is none.
+func MimeForName(name string) string {
+	return extensionTable[strings.ToLower(path.Ext(name))]
+}
+
+// MimeTypes returns the sorted list of mime types with a registered Viewer, excluding wildcards.
+func MimeTypes() []string {
+	var types []string
+	for m := range generatorTable {
+		if strings.Contains(m, "*") {
+			continue
+		}
+		types = append(types, m)
+	}
+	sort.Strings(types)
+	return types
 }
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package viewer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestDetectMime(t *testing.T) {
+	for name, tt := range map[string]struct {
+		name     string
+		declared string
+		data     []byte
+		want     string
+	}{
+		"Declared":             {"notes.txt", "text/plain", []byte("Hello World"), "text/plain"},
+		"Declared_Parameters":  {"notes.txt", "Text/Plain; charset=utf-8", []byte("Hello World"), "text/plain"},
+		"Declared_NoContent":   {"photo", "image/png", nil, "image/png"},
+		"Declared_Textual":     {"data", "application/json", []byte(`{"a": 1}`), "application/json"},
+		"Missing_Extension":    {"main.go", "", []byte("package main\n"), "text/x-go"},
+		"Missing_Sniffed":      {"photo", "", pngHeader, "image/png"},
+		"OctetStream":          {"main.go", MIME_TYPE_APPLICATION_OCTET_STREAM, []byte("package main\n"), "text/x-go"},
+		"Contradicted":         {"photo.txt", "text/plain", pngHeader, "image/png"},
+		"Contradicted_Binary":  {"photo.png", "text/plain", pngHeader, "image/png"},
+		"Contradicted_Textual": {"notes.txt", "image/png", []byte("Hello World"), "text/plain"},
+		"Unknown":              {"", "", nil, MIME_TYPE_APPLICATION_OCTET_STREAM},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectMime(tt.name, tt.declared, tt.data))
+		})
+	}
+}
+
+func TestRegisterTextual(t *testing.T) {
+	defer delete(textualTable, "application/x-custom")
+	assert.Equal(t, "text/plain", DetectMime("notes", "application/x-custom", []byte("Hello World")))
+	RegisterTextual("Application/X-Custom")
+	assert.Equal(t, "application/x-custom", DetectMime("notes", "application/x-custom", []byte("Hello World")))
+	// Binary content still contradicts the type
+	assert.Equal(t, "image/png", DetectMime("notes", "application/x-custom", pngHeader))
+}
+
+// withGenerators runs the test with an empty table of generators, restoring the registered Viewers afterwards.
+func withGenerators(t *testing.T, test func(t *testing.T)) {
+	t.Helper()
+	defer func(table map[string][]*registration, count int) {
+		generatorTable = table
+		registrations = count
+	}(generatorTable, registrations)
+	generatorTable = map[string][]*registration{}
+	test(t)
+}
+
+// namedGenerator returns a generator of TextPlainViewers with the given text, so tests can tell generators apart.
+func namedGenerator(text string) func() (Viewer, error) {
+	return func() (Viewer, error) {
+		v := NewTextPlainViewer()
+		v.text = text
+		return v, nil
+	}
+}
+
+func TestViewersFor(t *testing.T) {
+	withGenerators(t, func(t *testing.T) {
+		RegisterViewer("*/*", "Hex", PRIORITY_FALLBACK, namedGenerator("Hex"))
+		RegisterViewer("text/*", "Text", PRIORITY_DEFAULT, namedGenerator("Text"))
+		RegisterViewer("text/plain", "Plain", PRIORITY_DEFAULT, namedGenerator("Plain"))
+		RegisterViewer("text/plain", "Text", PRIORITY_DEFAULT, namedGenerator("Text"))
+		RegisterViewer("text/markdown", "Markdown", PRIORITY_PREFERRED, namedGenerator("Markdown"))
+		RegisterViewer("*/*", "Other", PRIORITY_DEFAULT, namedGenerator("Other"))
+
+		for name, tt := range map[string]struct {
+			mime string
+			want []string
+		}{
+			// Exact matches are preferred over wildcards, ties are broken by the order of registration, and names are only listed once
+			"Exact":      {"text/plain", []string{"Plain", "Text", "Other", "Hex"}},
+			"Parameters": {"Text/Plain; charset=utf-8", []string{"Plain", "Text", "Other", "Hex"}},
+			// Priority is preferred over an exact match
+			"Priority": {"text/markdown", []string{"Markdown", "Text", "Other", "Hex"}},
+			"Wildcard": {"text/csv", []string{"Text", "Other", "Hex"}},
+			"Any":      {"image/png", []string{"Other", "Hex"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.Equal(t, tt.want, ViewersFor(tt.mime))
+			})
+		}
+
+		v, err := ForMime("text/plain")
+		assert.Nil(t, err)
+		assert.Equal(t, "Plain", v.(*TextPlainViewer).text)
+
+		v, err = ForViewer("text/plain", "Hex")
+		assert.Nil(t, err)
+		assert.Equal(t, "Hex", v.(*TextPlainViewer).text)
+
+		_, err = ForViewer("text/plain", "Markdown")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestViewersFor_None(t *testing.T) {
+	withGenerators(t, func(t *testing.T) {
+		assert.Empty(t, ViewersFor("text/plain"))
+		_, err := ForMime("text/plain")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRegisterViewer_Replace(t *testing.T) {
+	withGenerators(t, func(t *testing.T) {
+		RegisterViewer("text/plain", "Text", PRIORITY_DEFAULT, namedGenerator("First"))
+		RegisterViewer("text/plain", "Other", PRIORITY_DEFAULT, namedGenerator("Other"))
+		RegisterViewer("text/plain", "Text", PRIORITY_DEFAULT, namedGenerator("Second"))
+		// Replacement is ordered as the latest registration
+		assert.Equal(t, []string{"Other", "Text"}, ViewersFor("text/plain"))
+		v, err := ForViewer("text/plain", "Text")
+		assert.Nil(t, err)
+		assert.Equal(t, "Second", v.(*TextPlainViewer).text)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	withGenerators(t, func(t *testing.T) {
+		Register("text/plain", namedGenerator("First"))
+		Register("text/plain", namedGenerator("Second"))
+		Register("text/markdown", namedGenerator("Markdown"))
+		assert.Equal(t, []string{DEFAULT_VIEWER_NAME}, ViewersFor("text/plain"))
+		v, err := ForMime("text/plain")
+		assert.Nil(t, err)
+		assert.Equal(t, "Second", v.(*TextPlainViewer).text)
+	})
+}